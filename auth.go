@@ -0,0 +1,202 @@
+package structuredhttp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BasicAuth sets the Authorization header to use basic authentication.
+func (r *Request) BasicAuth(Username, Password string) *Request {
+	return r.Header("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(Username+":"+Password)))
+}
+
+// BearerToken sets the Authorization header to use a bearer token.
+func (r *Request) BearerToken(Token string) *Request {
+	return r.Header("Authorization", "Bearer "+Token)
+}
+
+// Token is an access token which is sent in the Authorization header.
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type,omitempty"`
+	Expiry      time.Time `json:"expiry,omitempty"`
+}
+
+// Type returns the type of the token, defaulting to Bearer.
+func (t *Token) Type() string {
+	if t.TokenType == "" {
+		return "Bearer"
+	}
+	return t.TokenType
+}
+
+// Valid checks if the token is set and does not expire within the next 10 seconds. Tokens with no expiry are always
+// valid.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(10*time.Second).Before(t.Expiry)
+}
+
+// ErrNoToken is returned when a token source returns neither a token nor an error.
+var ErrNoToken = errors.New("structuredhttp: token source returned no token")
+
+// TokenSource gets a token for a request.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc is a function which implements TokenSource.
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token implements TokenSource.
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource returns a token source which always returns the same token.
+func StaticTokenSource(t *Token) TokenSource {
+	return TokenSourceFunc(func(context.Context) (*Token, error) {
+		return t, nil
+	})
+}
+
+// tokenCall is a token which is being fetched.
+type tokenCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// CachedTokenSource caches the tokens from another source until they are no longer valid. If many requests need a
+// token at the same time, it is only fetched once. The token is fetched with a context which is not cancelled with the
+// request which started it, so cancelling one request does not fail the others waiting for the token.
+type CachedTokenSource struct {
+	Source TokenSource
+
+	// Timeout is the longest fetching a token can take. This defaults to 30 seconds.
+	Timeout time.Duration
+
+	lock  sync.Mutex
+	token *Token
+	call  *tokenCall
+}
+
+// ReuseTokenSource creates a token source which caches the tokens from the source specified.
+func ReuseTokenSource(Source TokenSource) *CachedTokenSource {
+	return &CachedTokenSource{Source: Source}
+}
+
+// Token implements TokenSource.
+func (c *CachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	c.lock.Lock()
+	if c.token.Valid() {
+		Token := c.token
+		c.lock.Unlock()
+		return Token, nil
+	}
+
+	// Start fetching the token if it is not already being fetched.
+	Call := c.call
+	if Call == nil {
+		Call = &tokenCall{done: make(chan struct{})}
+		c.call = Call
+		go c.fetch(context.WithoutCancel(ctx), Call)
+	}
+	c.lock.Unlock()
+
+	// Wait for the token.
+	select {
+	case <-Call.done:
+		return Call.token, Call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch is used to fetch a token from the source for the call. A panic in the source is returned as an error, so the
+// requests waiting for the token are not left waiting.
+func (c *CachedTokenSource) fetch(ctx context.Context, Call *tokenCall) {
+	defer func() {
+		if v := recover(); v != nil {
+			Call.token, Call.err = nil, fmt.Errorf("structuredhttp: token source panicked: %v", v)
+		}
+		c.lock.Lock()
+		c.call = nil
+		if Call.err == nil {
+			c.token = Call.token
+		}
+		c.lock.Unlock()
+		close(Call.done)
+	}()
+	Timeout := c.Timeout
+	if Timeout == 0 {
+		Timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	Call.token, Call.err = c.Source.Token(ctx)
+}
+
+// Invalidate removes a token from the cache so the next call to Token fetches a new one. Nothing is removed if a
+// different token has been cached since, so a token which was rejected by many requests is only refreshed once.
+func (c *CachedTokenSource) Invalidate(t *Token) {
+	c.lock.Lock()
+	if c.token == t {
+		c.token = nil
+	}
+	c.lock.Unlock()
+}
+
+// Auth sets the Authorization header from the token source when the request is sent. If the server responds with
+// 401 Unauthorized and the source can be invalidated (such as a CachedTokenSource), a new token is fetched and the
+// request is sent once more. Requests with a body which cannot be replayed are not sent again.
+func (r *Request) Auth(Source TokenSource) *Request {
+	return r.Use(func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			Body, err := r.replayableBody(false)
+			if err != nil && err != ErrBodyNotRewindable {
+				return nil, err
+			}
+			for Attempt := 1; ; Attempt++ {
+				// Set the body from the start.
+				if Body != nil {
+					if r.CurrentReader, err = Body(); err != nil {
+						return nil, err
+					}
+				}
+
+				// Send the request with the current token.
+				Current, err := Source.Token(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if Current == nil {
+					return nil, ErrNoToken
+				}
+				r.Headers["Authorization"] = Current.Type() + " " + Current.AccessToken
+				res, err := next(ctx, r)
+				if err != nil || res.RawResponse.StatusCode != http.StatusUnauthorized || Attempt == 2 || Body == nil {
+					return res, err
+				}
+				Invalidator, ok := Source.(interface{ Invalidate(*Token) })
+				if !ok {
+					return res, nil
+				}
+
+				// Drain and close the body so the connection can be reused.
+				_, _ = io.Copy(ioutil.Discard, res.RawResponse.Body)
+				_ = res.RawResponse.Body.Close()
+				Invalidator.Invalidate(Current)
+			}
+		}
+	})
+}
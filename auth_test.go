@@ -0,0 +1,206 @@
+package structuredhttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "jake" || pass != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	response, err := GET(server.URL).BasicAuth("jake", "hunter2").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if err = response.RaiseForStatus(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var current int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+strconv.Itoa(int(atomic.LoadInt32(&current))) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+
+	// Start with a token the server does not accept.
+	var fetches int32
+	source := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		return &Token{AccessToken: strconv.Itoa(int(n) - 1)}, nil
+	}))
+	handler := RouteHandler{BaseURL: server.URL, TokenSource: source}
+
+	// Many requests rejected at once should only refresh the token once.
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := handler.POST("/").Bytes([]byte("hello")).Run()
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+			if err = response.RaiseForStatus(); err != nil {
+				t.Error(err.Error())
+				return
+			}
+			if text, _ := response.Text(); text != "hello" {
+				t.Error("Expected the body to be sent again, got", text)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches != 2 {
+		t.Error("Expected the token to be fetched twice, got", fetches)
+	}
+
+	// A source which cannot be invalidated is not retried.
+	handler.TokenSource = StaticTokenSource(&Token{AccessToken: "0"})
+	response, err := handler.GET("/").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("Expected 401, got", response.RawResponse.StatusCode)
+	}
+}
+
+func TestCachedTokenSourceCancelAndPanic(t *testing.T) {
+	// The first caller is cancelled while the token is being fetched, but the fetch still finishes for the others.
+	release := make(chan struct{})
+	source := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		select {
+		case <-release:
+			return &Token{AccessToken: "token"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := source.Token(ctx)
+		first <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	second := make(chan *Token)
+	go func() {
+		token, err := source.Token(context.Background())
+		if err != nil {
+			t.Error(err.Error())
+		}
+		second <- token
+	}()
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Error("Expected the first caller to be cancelled, got", err)
+	}
+	close(release)
+	if token := <-second; token == nil || token.AccessToken != "token" {
+		t.Error("Expected the other caller to get the token, got", token)
+	}
+
+	// A panic in the source is returned as an error, and later calls fetch again.
+	panics := true
+	source = ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		if panics {
+			panics = false
+			panic("broken")
+		}
+		return &Token{AccessToken: "token"}, nil
+	}))
+	if _, err := source.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Error("Expected the panic as an error, got", err)
+	}
+	if token, err := source.Token(context.Background()); err != nil || token.AccessToken != "token" {
+		t.Error("Expected the token to be fetched again, got", token, err)
+	}
+
+	// The fetch is limited by the timeout.
+	source = ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	source.Timeout = 10 * time.Millisecond
+	if _, err := source.Token(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected the fetch to time out, got", err)
+	}
+}
+
+func TestTokenSourceBeforeMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Each middleware records the Authorization header it sees.
+	var seen []string
+	record := func(name string) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, r *Request) (*Response, error) {
+				seen = append(seen, name+" "+r.Headers["Authorization"])
+				return next(ctx, r)
+			}
+		}
+	}
+	client := NewClient()
+	client.TokenSource = StaticTokenSource(&Token{AccessToken: "client"})
+	client.Middleware = []Middleware{record("client")}
+	if _, err := client.GET(server.URL).Use(record("request")).Run(); err != nil {
+		t.Fatal(err)
+	}
+	handler := RouteHandler{
+		BaseURL:     server.URL,
+		Client:      client,
+		Middleware:  []Middleware{record("handler")},
+		TokenSource: StaticTokenSource(&Token{AccessToken: "handler"}),
+	}
+	if _, err := handler.GET("/").Run(); err != nil {
+		t.Fatal(err)
+	}
+	handler.Client = nil
+	if _, err := handler.GET("/").Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"client Bearer client", "request Bearer client",
+		"client Bearer handler", "handler Bearer handler",
+		"handler Bearer handler",
+	}
+	if strings.Join(seen, ",") != strings.Join(expected, ",") {
+		t.Error("Expected", expected, "got", seen)
+	}
+}
+
+func TestAuthNoToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("The request was sent without a token.")
+	}))
+	defer server.Close()
+
+	if _, err := GET(server.URL).Auth(StaticTokenSource(nil)).Run(); !errors.Is(err, ErrNoToken) {
+		t.Error("Expected ErrNoToken, got", err)
+	}
+}
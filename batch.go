@@ -1,6 +1,10 @@
 package structuredhttp
 
 import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
 	"sync"
 	"sync/atomic"
 )
@@ -8,49 +12,243 @@ import (
 // Batch is used to define a request batch.
 type Batch []*Request
 
-// All is used to execute a batch job and return the pure responses. If one fails, the first error is returned.
-func (b Batch) All() ([]*Response, error) {
-	// Get the results of each request.
-	results := make([]interface{}, len(b))
+// ErrBatchCancelled is the error for requests in a fail-fast batch which were not sent because another request failed.
+var ErrBatchCancelled = errors.New("the request was not sent because another request in the batch failed")
+
+// BatchOptions defines how a batch is executed.
+type BatchOptions struct {
+	// MaxConcurrency is the maximum number of requests which are sent at once. 0 is unlimited.
+	MaxConcurrency int
+
+	// FailFast cancels the requests which are still running when a request fails. Requests which have not been sent
+	// yet fail with ErrBatchCancelled.
+	FailFast bool
+
+	// Context is the context the requests are ran with. If a request has its own context, both are used.
+	Context context.Context
+}
+
+// requestContext is used to get the context which a request in the batch runs with.
+func (o BatchOptions) requestContext(r *Request) (context.Context, context.CancelFunc) {
+	ctx := r.CurrentContext
+	if ctx == nil {
+		ctx = o.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	if o.Context != nil && r.CurrentContext != nil {
+		// Also cancel the request when the batch context is done.
+		stop := context.AfterFunc(o.Context, cancel)
+		return ctx, func() {
+			stop()
+			cancel()
+		}
+	}
+	return ctx, cancel
+}
+
+// cancelBody wraps the body of a response in a batch to cancel the context of the request once the body is read or
+// closed, rather than when the batch is done.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Read implements io.Reader.
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.cancel()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// BatchError is returned when requests in a batch fail.
+type BatchError struct {
+	// Errors has the error for each request in the batch, which is nil if the request succeeded.
+	Errors []error
+
+	// first is the index of the request which failed first.
+	first int
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	Failed := 0
+	for _, v := range e.Errors {
+		if v != nil {
+			Failed++
+		}
+	}
+	return strconv.Itoa(Failed) + " of " + strconv.Itoa(len(e.Errors)) +
+		" requests in the batch failed, the first error was: " + e.First().Error()
+}
+
+// First returns the error from the request which failed first. With FailFast, this is the error which cancelled the
+// other requests.
+func (e *BatchError) First() error {
+	return e.Errors[e.first]
+}
+
+// firstByIndex is used to get the error from the request with the lowest index which failed.
+func (e *BatchError) firstByIndex() error {
+	for _, v := range e.Errors {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// Unwrap returns the errors from the requests which failed.
+func (e *BatchError) Unwrap() []error {
+	Errors := make([]error, 0, len(e.Errors))
+	for _, v := range e.Errors {
+		if v != nil {
+			Errors = append(Errors, v)
+		}
+	}
+	return Errors
+}
+
+// execute is used to execute a batch job with the options specified. Done is called from the worker for each request
+// when it completes. The index of the first request which failed is returned, or -1 if none did.
+func (b Batch) execute(Options BatchOptions, Done func(i int, res *Response, err error)) int {
+	// Defines the state shared between workers.
+	lock := sync.Mutex{}
+	Running := map[int]context.CancelFunc{}
+	First := -1
+	Next := int64(-1)
+
+	// Start the workers. Each takes the next request until there are none left.
+	Workers := len(b)
+	if Options.MaxConcurrency > 0 && Options.MaxConcurrency < Workers {
+		Workers = Options.MaxConcurrency
+	}
 	wg := sync.WaitGroup{}
-	wg.Add(len(b))
-	for i, v := range b {
-		// Create a pointer to the result.
-		ptr := &results[i]
-
-		// Create a goroutine to do the request.
-		go func(req *Request) {
-			// Defer the request as done.
+	wg.Add(Workers)
+	for w := 0; w < Workers; w++ {
+		go func() {
+			// Defer the worker as done.
 			defer wg.Done()
 
-			// Run the request.
-			res, err := req.Run()
-			if err != nil {
-				*ptr = err
-				return
+			for {
+				i := int(atomic.AddInt64(&Next, 1))
+				if i >= len(b) {
+					return
+				}
+
+				// Skip the request if the batch has failed fast.
+				lock.Lock()
+				if Options.FailFast && First != -1 {
+					lock.Unlock()
+					Done(i, nil, ErrBatchCancelled)
+					continue
+				}
+				ctx, cancel := Options.requestContext(b[i])
+				Running[i] = cancel
+				lock.Unlock()
+
+				// Run the request.
+				res, err := b[i].RunContext(ctx)
+
+				// Handle the result. The context of successful requests is cancelled once the body is read or closed, so
+				// it can still be read until then.
+				lock.Lock()
+				delete(Running, i)
+				if err == nil {
+					if res != nil && res.RawResponse != nil && res.RawResponse.Body != nil {
+						res.RawResponse.Body = &cancelBody{ReadCloser: res.RawResponse.Body, cancel: cancel}
+					} else {
+						cancel()
+					}
+				} else {
+					cancel()
+					if First == -1 {
+						First = i
+						if Options.FailFast {
+							for _, f := range Running {
+								f()
+							}
+						}
+					}
+				}
+				lock.Unlock()
+				Done(i, res, err)
 			}
-			*ptr = res
-		}(v)
+		}()
 	}
 	wg.Wait()
+	return First
+}
 
-	// Un-generic the results and return them.
-	ungeneric := make([]*Response, len(results))
-	for i, v := range results {
-		if err, ok := v.(error); ok {
-			return nil, err
-		}
-		ungeneric[i] = v.(*Response)
+// Run is used to execute a batch job with the options specified. All the responses are returned, with nil for the
+// requests which failed. If any requests failed, the error is a *BatchError.
+func (b Batch) Run(Options BatchOptions) ([]*Response, error) {
+	Responses := make([]*Response, len(b))
+	Errors := make([]error, len(b))
+	First := b.execute(Options, func(i int, res *Response, err error) {
+		Responses[i], Errors[i] = res, err
+	})
+	if First != -1 {
+		return Responses, &BatchError{Errors: Errors, first: First}
+	}
+	return Responses, nil
+}
+
+// BatchResult is the result of a request in a batch.
+type BatchResult struct {
+	Index    int
+	Response *Response
+	Error    error
+}
+
+// Stream is used to execute a batch job with the options specified, sending the result of each request on the channel
+// as it completes. The channel is closed when all the requests are done. It has room for every result, so the batch
+// still finishes if the channel is not read to the end.
+func (b Batch) Stream(Options BatchOptions) <-chan BatchResult {
+	Results := make(chan BatchResult, len(b))
+	go func() {
+		defer close(Results)
+		b.execute(Options, func(i int, res *Response, err error) {
+			Results <- BatchResult{Index: i, Response: res, Error: err}
+		})
+	}()
+	return Results
+}
+
+// All is used to execute a batch job and return the pure responses. If any fail, the error of the first one in the
+// batch is returned.
+func (b Batch) All() ([]*Response, error) {
+	Responses, err := b.Run(BatchOptions{})
+	if err != nil {
+		return nil, err.(*BatchError).firstByIndex()
 	}
-	return ungeneric, nil
+	return Responses, nil
 }
 
 // BatchMapper is used to handle mapping a Batch to values.
 type BatchMapper struct {
 	b Batch
+	o BatchOptions
 	f []func(interface{}) (interface{}, error)
 }
 
+// Options is used to set the options the batch is executed with.
+func (b *BatchMapper) Options(Options BatchOptions) *BatchMapper {
+	b.o = Options
+	return b
+}
+
 // Map is used to handle adding a mapper function to the chain.
 func (b *BatchMapper) Map(f func(interface{}) (interface{}, error)) *BatchMapper {
 	b.f = append(b.f, f)
@@ -69,11 +267,12 @@ func (b Batch) Map(f func(*Response) (interface{}, error)) *BatchMapper {
 	}
 }
 
-// All is used to execute a batch job and return the mapped responses. If one fails, the first error is returned.
+// All is used to execute a batch job and return the mapped responses. If any requests fail, the error of the first
+// one in the batch is returned.
 func (b *BatchMapper) All() ([]interface{}, error) {
-	responses, err := b.b.All()
+	responses, err := b.b.Run(b.o)
 	if err != nil {
-		return nil, err
+		return nil, err.(*BatchError).firstByIndex()
 	}
 	done := make([]interface{}, len(responses))
 	var errSet uintptr
@@ -127,3 +326,65 @@ func (b *BatchMapper) All() ([]interface{}, error) {
 	}
 	return done, nil
 }
+
+// Run is used to execute a batch job and return all the mapped values, with nil for the requests which failed or
+// could not be mapped. If any did, the error is a *BatchError with the error for each request.
+func (b *BatchMapper) Run() ([]interface{}, error) {
+	Values := make([]interface{}, len(b.b))
+	Errors := make([]error, len(b.b))
+	lock := sync.Mutex{}
+	First := -1
+	b.b.execute(b.o, func(i int, res *Response, err error) {
+		if err == nil {
+			Values[i], err = b.apply(res)
+		}
+		if err != nil {
+			lock.Lock()
+			Errors[i] = err
+			if First == -1 {
+				First = i
+			}
+			lock.Unlock()
+		}
+	})
+	if First != -1 {
+		return Values, &BatchError{Errors: Errors, first: First}
+	}
+	return Values, nil
+}
+
+// apply is used to run a value through the mapper chain.
+func (b *BatchMapper) apply(v interface{}) (interface{}, error) {
+	var err error
+	for _, f := range b.f {
+		if v, err = f(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// BatchMappedResult is the mapped result of a request in a batch.
+type BatchMappedResult struct {
+	Index int
+	Value interface{}
+	Error error
+}
+
+// Stream is used to execute a batch job, sending the mapped result of each request on the channel as it completes.
+// The mapper chain is ran for each response as soon as it is done. The channel is closed when all the requests are
+// done. It has room for every result, so the batch still finishes if the channel is not read to the end.
+func (b *BatchMapper) Stream() <-chan BatchMappedResult {
+	Results := make(chan BatchMappedResult, len(b.b))
+	go func() {
+		defer close(Results)
+		b.b.execute(b.o, func(i int, res *Response, err error) {
+			var v interface{}
+			if err == nil {
+				v, err = b.apply(res)
+			}
+			Results <- BatchMappedResult{Index: i, Value: v, Error: err}
+		})
+	}()
+	return Results
+}
@@ -0,0 +1,210 @@
+package structuredhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchConcurrency(t *testing.T) {
+	var current, max int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&current, 1)
+		for {
+			m := atomic.LoadInt64(&max)
+			if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&current, -1)
+	}))
+	defer server.Close()
+
+	batch := Batch{}
+	for i := 0; i < 10; i++ {
+		batch = append(batch, GET(server.URL))
+	}
+	responses, err := batch.Run(BatchOptions{MaxConcurrency: 2})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if len(responses) != 10 || max > 2 {
+		t.Error("Expected at most 2 requests at once, got", max)
+		return
+	}
+	t.Log("Concurrency was limited.")
+}
+
+func TestBatchFailFast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	failure := errors.New("failure")
+	failing := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, failure
+		}
+	}
+	batch := Batch{GET(server.URL + "/slow"), GET(server.URL).Use(failing), GET(server.URL + "/slow")}
+	start := time.Now()
+	_, err := batch.Run(BatchOptions{MaxConcurrency: 2, FailFast: true})
+	if time.Since(start) > 5*time.Second {
+		t.Error("The batch did not fail fast.")
+		return
+	}
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Error("Expected a *BatchError, got", err)
+		return
+	}
+	if batchErr.First() != failure || !errors.Is(err, failure) {
+		t.Error("Invalid first error", batchErr.First())
+		return
+	}
+	if !errors.Is(batchErr.Errors[0], context.Canceled) || batchErr.Errors[2] != ErrBatchCancelled {
+		t.Error("Invalid errors for cancelled requests", batchErr.Errors)
+		return
+	}
+
+	responses, err := Batch{GET(server.URL + "/fast"), GET(server.URL).Use(failing)}.Run(BatchOptions{})
+	if !errors.As(err, &batchErr) || responses[0] == nil || batchErr.Errors[0] != nil {
+		t.Error("Expected the successful response to be returned.")
+		return
+	}
+	t.Log("Batch failed fast.")
+}
+
+func TestBatchStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	batch := Batch{GET(server.URL + "/slow"), GET(server.URL + "/fast")}
+	var order []int
+	for result := range batch.Map(func(r *Response) (interface{}, error) {
+		return r.Text()
+	}).Stream() {
+		if result.Error != nil {
+			t.Error(result.Error.Error())
+			return
+		}
+		if result.Value.(string) != []string{"/slow", "/fast"}[result.Index] {
+			t.Error("Invalid value for index", result.Index)
+			return
+		}
+		order = append(order, result.Index)
+	}
+	if len(order) != 2 || order[0] != 1 {
+		t.Error("Expected the fast request to be streamed first, got", order)
+		return
+	}
+	t.Log("Batch streamed results.")
+}
+
+func TestBatchReleasesContexts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	// Keep the context each request is sent with.
+	contexts := make([]context.Context, 2)
+	keep := func(i int) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, r *Request) (*Response, error) {
+				contexts[i] = ctx
+				return next(ctx, r)
+			}
+		}
+	}
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batch := Batch{
+		GET(server.URL).Use(keep(0)),
+		GET(server.URL).Context(context.Background()).Use(keep(1)),
+	}
+	responses, err := batch.Run(BatchOptions{Context: parent})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// The contexts are released once each body is read, but not before.
+	for i, res := range responses {
+		if contexts[i].Err() != nil {
+			t.Error("The context of request", i, "was cancelled before the body was read.")
+		}
+		if text, err := res.Text(); err != nil || text != "hello" {
+			t.Error("Invalid body", text, err)
+		}
+		if contexts[i].Err() == nil {
+			t.Error("The context of request", i, "was not released after the body was read.")
+		}
+	}
+}
+
+func TestBatchAllIndexOrder(t *testing.T) {
+	slow, fast := errors.New("slow"), errors.New("fast")
+	failing := func(err error, delay time.Duration) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, r *Request) (*Response, error) {
+				time.Sleep(delay)
+				return nil, err
+			}
+		}
+	}
+	batch := Batch{GET("http://example.com").Use(failing(slow, 50*time.Millisecond)),
+		GET("http://example.com").Use(failing(fast, 0))}
+	if _, err := batch.All(); err != slow {
+		t.Error("Expected the error of the first request, got", err)
+	}
+	_, err := batch.Map(func(r *Response) (interface{}, error) { return r, nil }).All()
+	if err != slow {
+		t.Error("Expected the mapper to return the error of the first request, got", err)
+	}
+}
+
+func TestBatchStreamStopEarly(t *testing.T) {
+	var done int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	count := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			defer atomic.AddInt64(&done, 1)
+			return next(ctx, r)
+		}
+	}
+	batch := Batch{}
+	for i := 0; i < 5; i++ {
+		batch = append(batch, GET(server.URL).Use(count))
+	}
+
+	// Only read the first result. The rest of the batch still runs.
+	results := batch.Stream(BatchOptions{MaxConcurrency: 1})
+	<-results
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&done) != 5 {
+		if time.Now().After(deadline) {
+			t.Error("The batch was blocked by the unread results.")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
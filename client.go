@@ -0,0 +1,135 @@
+package structuredhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// Credentials is the fetch credentials mode used for requests on WASM.
+type Credentials string
+
+const (
+	// CredentialsOmit never sends or stores cookies.
+	CredentialsOmit Credentials = "omit"
+
+	// CredentialsSameOrigin only sends and stores cookies for the same origin. This is the browser default.
+	CredentialsSameOrigin Credentials = "same-origin"
+
+	// CredentialsInclude sends and stores cookies for every origin.
+	CredentialsInclude Credentials = "include"
+)
+
+// Client defines a reusable set of defaults which are applied to every request made with it. Requests made with the
+// same client share its transport, so keep-alive connections are reused between them.
+type Client struct {
+	Transport  http.RoundTripper  `json:"-"`
+	Timeout    *time.Duration     `json:"-"`
+	Headers    map[string]string  `json:"headers"`
+	Plugins    []func(r *Request) `json:"-"`
+	Middleware []Middleware       `json:"-"`
+
+	// TokenSource is used to authenticate every request made with this client. The Authorization header is set before
+	// any middleware runs. See Request.Auth.
+	TokenSource TokenSource `json:"-"`
+
+	// ProxySelector chooses the proxy for each request. If this is nil, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables are used. This is ignored if the transport is not from NewClient, and on WASM.
+	ProxySelector ProxySelector `json:"-"`
+
+	// Dialer opens the connections for requests made with this client. This is ignored if the transport is not from
+	// NewClient, and on WASM.
+	Dialer Dialer `json:"-"`
+
+	// Redirects is the redirect policy of requests made with this client. If this is nil, up to 10 redirects are
+	// followed. On WASM, only Disable is used.
+	Redirects *RedirectPolicy `json:"redirects,omitempty"`
+
+	// Jar stores the cookies sent and received by requests made with this client. On WASM, the browser stores
+	// cookies itself, so setting this only changes the default credentials mode to include.
+	Jar http.CookieJar `json:"-"`
+
+	// Credentials is the fetch credentials mode used on WASM. This is ignored on other platforms.
+	Credentials Credentials `json:"credentials,omitempty"`
+}
+
+// NewClient creates a client with its own transport and connection pool.
+func NewClient() *Client {
+	return &Client{
+		Transport: newContextTransport(),
+		Headers:   map[string]string{},
+	}
+}
+
+// NewSession creates a client with an empty cookie jar, so cookies set by responses are sent with later requests.
+func NewSession() *Client {
+	c := NewClient()
+	c.Jar = NewCookieJar()
+	return c
+}
+
+// apply is used to apply the clients defaults and middleware to a request.
+func (c *Client) apply(req *Request) *Request {
+	return c.use(c.defaults(req), c.TokenSource)
+}
+
+// use is used to add the client middleware to a request. The token source is added first, so the Authorization
+// header is set before any middleware runs.
+func (c *Client) use(req *Request, Source TokenSource) *Request {
+	if Source != nil {
+		req = req.Auth(Source)
+	}
+	return req.Use(c.Middleware...)
+}
+
+// defaults is used to apply the clients defaults to a request, without its middleware.
+func (c *Client) defaults(req *Request) *Request {
+	req.CurrentClient = c
+	if c.Timeout != nil {
+		req = req.Timeout(*c.Timeout)
+	}
+	for k, v := range c.Headers {
+		req = req.Header(k, v)
+	}
+	for _, f := range c.Plugins {
+		req = req.Plugin(f)
+	}
+	if c.Redirects != nil {
+		req = req.Redirects(*c.Redirects)
+	}
+	return req
+}
+
+// GET adds support for a GET request using this client.
+func (c *Client) GET(URL string) *Request {
+	return c.apply(GET(URL))
+}
+
+// POST adds support for a POST request using this client.
+func (c *Client) POST(URL string) *Request {
+	return c.apply(POST(URL))
+}
+
+// PUT adds support for a PUT request using this client.
+func (c *Client) PUT(URL string) *Request {
+	return c.apply(PUT(URL))
+}
+
+// PATCH adds support for a PATCH request using this client.
+func (c *Client) PATCH(URL string) *Request {
+	return c.apply(PATCH(URL))
+}
+
+// DELETE adds support for a DELETE request using this client.
+func (c *Client) DELETE(URL string) *Request {
+	return c.apply(DELETE(URL))
+}
+
+// OPTIONS adds support for a OPTIONS request using this client.
+func (c *Client) OPTIONS(URL string) *Request {
+	return c.apply(OPTIONS(URL))
+}
+
+// HEAD adds support for a HEAD request using this client.
+func (c *Client) HEAD(URL string) *Request {
+	return c.apply(HEAD(URL))
+}
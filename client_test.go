@@ -0,0 +1,41 @@
+package structuredhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	addrs := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addrs[r.RemoteAddr] = true
+		_, _ = w.Write([]byte(r.Header.Get("X-Client")))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Headers["X-Client"] = "hello"
+	handler := RouteHandler{BaseURL: server.URL, Client: client}
+	for i := 0; i < 3; i++ {
+		response, err := handler.GET("/").Run()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		text, err := response.Text()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if text != "hello" {
+			t.Error("Invalid string returned (" + text + ").")
+			return
+		}
+	}
+	if len(addrs) != 1 {
+		t.Error("Expected the connection to be reused, got", len(addrs), "connections.")
+		return
+	}
+	t.Log("Client works!")
+}
@@ -0,0 +1,42 @@
+package structuredhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := GET(server.URL).RunContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected the request to be cancelled, got", err)
+		return
+	}
+	t.Log("Request was cancelled.")
+}
+
+func TestContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := GET(server.URL).Context(ctx).Run()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected the deadline to be exceeded, got", err)
+		return
+	}
+	t.Log("Deadline was exceeded.")
+}
@@ -0,0 +1,253 @@
+package structuredhttp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cookie is a cookie stored in a CookieJar.
+type Cookie struct {
+	Name       string    `json:"name"`
+	Value      string    `json:"value"`
+	Domain     string    `json:"domain"`
+	Path       string    `json:"path"`
+	Expires    time.Time `json:"expires,omitempty"`
+	Secure     bool      `json:"secure,omitempty"`
+	HTTPOnly   bool      `json:"http_only,omitempty"`
+	HostOnly   bool      `json:"host_only,omitempty"`
+	Persistent bool      `json:"persistent,omitempty"`
+	Created    time.Time `json:"created"`
+}
+
+// expired is used to check if the cookie has expired.
+func (c *Cookie) expired(Now time.Time) bool {
+	return c.Persistent && !c.Expires.After(Now)
+}
+
+// key is used to get the key the cookie is stored under.
+func (c *Cookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+// CookieJar is an RFC 6265 cookie jar which can be saved to and loaded from JSON. Session cookies are saved too, so
+// a login can be carried between runs.
+type CookieJar struct {
+	// PublicSuffixList is used to stop cookies being set for public suffixes such as "co.uk". If this is nil, only
+	// domains without a dot (such as "com") are treated as public suffixes.
+	PublicSuffixList cookiejar.PublicSuffixList
+
+	lock    sync.Mutex
+	cookies map[string]*Cookie
+}
+
+// NewCookieJar creates an empty cookie jar.
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: map[string]*Cookie{}}
+}
+
+// LoadCookieJar loads a cookie jar from the file specified. If the file does not exist, an empty jar is returned.
+func LoadCookieJar(Path string) (*CookieJar, error) {
+	j := NewCookieJar()
+	b, err := ioutil.ReadFile(Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return j, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(b, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Save saves the cookie jar to the file specified.
+func (j *CookieJar) Save(Path string) error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(Path, b, 0600)
+}
+
+// All returns every cookie in the jar which has not expired.
+func (j *CookieJar) All() []Cookie {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	Now := time.Now()
+	Cookies := []Cookie{}
+	for k, v := range j.cookies {
+		if v.expired(Now) {
+			delete(j.cookies, k)
+			continue
+		}
+		Cookies = append(Cookies, *v)
+	}
+	sort.Slice(Cookies, func(a, b int) bool { return Cookies[a].key() < Cookies[b].key() })
+	return Cookies
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j *CookieJar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.All())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *CookieJar) UnmarshalJSON(b []byte) error {
+	var Cookies []*Cookie
+	if err := json.Unmarshal(b, &Cookies); err != nil {
+		return err
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.cookies = map[string]*Cookie{}
+	for _, v := range Cookies {
+		j.cookies[v.key()] = v
+	}
+	return nil
+}
+
+// canonicalHost is used to get the lower case host of a URL without the port.
+func canonicalHost(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// domainMatch is used to check if a host is within a domain as defined in RFC 6265 section 5.1.3.
+func domainMatch(Host, Domain string) bool {
+	if Host == Domain {
+		return true
+	}
+	return strings.HasSuffix(Host, "."+Domain) && net.ParseIP(Host) == nil
+}
+
+// defaultPath is used to get the default path of a cookie as defined in RFC 6265 section 5.1.4.
+func defaultPath(Path string) string {
+	i := strings.LastIndex(Path, "/")
+	if i <= 0 || !strings.HasPrefix(Path, "/") {
+		return "/"
+	}
+	return Path[:i]
+}
+
+// pathMatch is used to check if a request path is within a cookie path as defined in RFC 6265 section 5.1.4.
+func pathMatch(Path, CookiePath string) bool {
+	if Path == "" {
+		Path = "/"
+	}
+	if !strings.HasPrefix(Path, CookiePath) {
+		return false
+	}
+	return len(Path) == len(CookiePath) || strings.HasSuffix(CookiePath, "/") || Path[len(CookiePath)] == '/'
+}
+
+// publicSuffix is used to check if a domain is a public suffix. Without a list, domains without a dot are.
+func (j *CookieJar) publicSuffix(Domain string) bool {
+	if j.PublicSuffixList == nil {
+		return !strings.Contains(Domain, ".")
+	}
+	return j.PublicSuffixList.PublicSuffix(Domain) == Domain
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, Cookies []*http.Cookie) {
+	Host := canonicalHost(u)
+	Now := time.Now()
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.cookies == nil {
+		j.cookies = map[string]*Cookie{}
+	}
+	for _, v := range Cookies {
+		if v.Name == "" {
+			continue
+		}
+		c := &Cookie{Name: v.Name, Value: v.Value, Secure: v.Secure, HTTPOnly: v.HttpOnly, Created: Now}
+
+		// Work out the domain. Cookies for a public suffix are only allowed on that exact host.
+		Domain := strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(v.Domain), "."), ".")
+		if Domain != "" && j.publicSuffix(Domain) {
+			if Domain != Host {
+				continue
+			}
+			Domain = ""
+		}
+		if Domain == "" {
+			c.Domain, c.HostOnly = Host, true
+		} else if domainMatch(Host, Domain) {
+			c.Domain = Domain
+		} else {
+			continue
+		}
+
+		// Work out the path.
+		c.Path = v.Path
+		if !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultPath(u.Path)
+		}
+
+		// Work out the expiry. Max-Age takes priority over Expires.
+		if v.MaxAge < 0 {
+			delete(j.cookies, c.key())
+			continue
+		} else if v.MaxAge > 0 {
+			c.Expires, c.Persistent = Now.Add(time.Duration(v.MaxAge)*time.Second), true
+		} else if !v.Expires.IsZero() {
+			c.Expires, c.Persistent = v.Expires, true
+		}
+		if c.expired(Now) {
+			delete(j.cookies, c.key())
+			continue
+		}
+
+		// Keep the creation time of a cookie being replaced.
+		if Old, ok := j.cookies[c.key()]; ok {
+			c.Created = Old.Created
+		}
+		j.cookies[c.key()] = c
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	Host := canonicalHost(u)
+	Secure := u.Scheme == "https" || u.Scheme == "wss"
+	Now := time.Now()
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	Matched := []*Cookie{}
+	for k, v := range j.cookies {
+		if v.expired(Now) {
+			delete(j.cookies, k)
+			continue
+		}
+		if v.HostOnly && Host != v.Domain || !v.HostOnly && !domainMatch(Host, v.Domain) {
+			continue
+		}
+		if !pathMatch(u.Path, v.Path) || v.Secure && !Secure {
+			continue
+		}
+		Matched = append(Matched, v)
+	}
+
+	// Cookies with longer paths are sent first, then older cookies.
+	sort.Slice(Matched, func(a, b int) bool {
+		if len(Matched[a].Path) != len(Matched[b].Path) {
+			return len(Matched[a].Path) > len(Matched[b].Path)
+		}
+		return Matched[a].Created.Before(Matched[b].Created)
+	})
+	Cookies := make([]*http.Cookie, len(Matched))
+	for i, v := range Matched {
+		Cookies[i] = &http.Cookie{Name: v.Name, Value: v.Value}
+	}
+	return Cookies
+}
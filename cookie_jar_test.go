@@ -0,0 +1,127 @@
+package structuredhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", MaxAge: 3600})
+			return
+		}
+		c, err := r.Cookie("session")
+		if err != nil || c.Value != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	// Log in and check the cookie is sent with the next request.
+	client := NewSession()
+	handler := RouteHandler{BaseURL: server.URL, Client: client}
+	if _, err := handler.POST("/login").Run(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	response, err := handler.GET("/me").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if err = response.RaiseForStatus(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// Save the jar and check a new session can use it.
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err = client.Jar.(*CookieJar).Save(path); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	jar, err := LoadCookieJar(path)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	client = NewClient()
+	client.Jar = jar
+	response, err = client.GET(server.URL + "/me").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if err = response.RaiseForStatus(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+}
+
+func TestCookieJarMatching(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://www.example.com/a/b")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "other", Value: "4", Domain: "other.com"},
+	})
+	tests := map[string][]string{
+		"https://www.example.com/a/c": {"host", "domain", "secure"},
+		"http://www.example.com/a":    {"host", "domain"},
+		"https://api.example.com/a":   {"domain"},
+		"https://www.example.com/ab":  {"domain", "secure"},
+		"https://other.com/":          {},
+	}
+	for URL, expected := range tests {
+		u, _ := url.Parse(URL)
+		cookies := jar.Cookies(u)
+		names := map[string]bool{}
+		for _, c := range cookies {
+			names[c.Name] = true
+		}
+		if len(cookies) != len(expected) {
+			t.Error("Expected", expected, "for", URL, "got", cookies)
+			continue
+		}
+		for _, name := range expected {
+			if !names[name] {
+				t.Error("Expected", expected, "for", URL, "got", cookies)
+			}
+		}
+	}
+	if cookies := jar.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/a/c"}); cookies[0].Name != "host" {
+		t.Error("Expected the cookie with the longest path first, got", cookies)
+	}
+
+	// Setting a cookie with a negative max age removes it.
+	jar.SetCookies(u, []*http.Cookie{{Name: "host", MaxAge: -1}})
+	if len(jar.All()) != 2 {
+		t.Error("Expected the cookie to be removed, got", jar.All())
+	}
+}
+
+func TestCookieJarPublicSuffix(t *testing.T) {
+	// Without a public suffix list, domains without a dot cannot be set by other hosts.
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://a.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "tld", Value: "1", Domain: "com"}})
+	for _, URL := range []string{"https://a.com/", "https://b.com/"} {
+		u, _ := url.Parse(URL)
+		if cookies := jar.Cookies(u); len(cookies) != 0 {
+			t.Error("Expected no cookies for", URL, "got", cookies)
+		}
+	}
+
+	// A host without a dot can still set a cookie for itself, which is host only.
+	u, _ = url.Parse("http://localhost/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "local", Value: "2", Domain: "localhost"}})
+	if cookies := jar.All(); len(cookies) != 1 || !cookies[0].HostOnly {
+		t.Error("Expected a host only cookie for localhost, got", cookies)
+	}
+}
@@ -0,0 +1,395 @@
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// CBORSerializer serializes request bodies as CBOR.
+var CBORSerializer = structuredhttp.Serializer{
+	Encode:      MarshalCBOR,
+	ContentType: "application/cbor",
+}
+
+// CBORParser parses CBOR response bodies.
+var CBORParser structuredhttp.Parser = UnmarshalCBOR
+
+// MarshalCBOR encodes a Go value as CBOR (RFC 8949). Struct fields are named with the cbor tag, falling back to the
+// json tag, and times are encoded as tagged RFC 3339 strings.
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	w := &cborWriter{}
+	if err := encode(w, reflect.ValueOf(v), "cbor", 0); err != nil {
+		return nil, err
+	}
+	return w.b, nil
+}
+
+// UnmarshalCBOR decodes CBOR into the Go value pointed to by into. When decoding into an interface, maps with text
+// keys become map[string]interface{}, integers become int64 (or uint64 if they do not fit), and tagged times become
+// time.Time.
+func UnmarshalCBOR(b []byte, into interface{}) error {
+	d := &cborDecoder{b: b}
+	Value, err := d.value(0)
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.b) {
+		return errors.New("data: trailing data after CBOR value")
+	}
+	return assigner{tag: "cbor"}.decodeInto(Value, into)
+}
+
+// cborWriter is used to write CBOR.
+type cborWriter struct {
+	b []byte
+}
+
+func (w *cborWriter) writeHead(Major byte, n uint64) {
+	Major <<= 5
+	switch {
+	case n < 24:
+		w.b = append(w.b, Major|byte(n))
+	case n <= math.MaxUint8:
+		w.b = append(w.b, Major|24, byte(n))
+	case n <= math.MaxUint16:
+		w.b = append(w.b, Major|25)
+		w.b = binary.BigEndian.AppendUint16(w.b, uint16(n))
+	case n <= math.MaxUint32:
+		w.b = append(w.b, Major|26)
+		w.b = binary.BigEndian.AppendUint32(w.b, uint32(n))
+	default:
+		w.b = append(w.b, Major|27)
+		w.b = binary.BigEndian.AppendUint64(w.b, n)
+	}
+}
+
+func (w *cborWriter) writeNil() {
+	w.b = append(w.b, 0xf6)
+}
+
+func (w *cborWriter) writeBool(v bool) {
+	if v {
+		w.b = append(w.b, 0xf5)
+	} else {
+		w.b = append(w.b, 0xf4)
+	}
+}
+
+func (w *cborWriter) writeInt(v int64) {
+	if v >= 0 {
+		w.writeHead(0, uint64(v))
+	} else {
+		w.writeHead(1, uint64(^v))
+	}
+}
+
+func (w *cborWriter) writeUint(v uint64) {
+	w.writeHead(0, v)
+}
+
+func (w *cborWriter) writeFloat(v float64, bits int) {
+	if bits == 32 {
+		w.b = append(w.b, 0xfa)
+		w.b = binary.BigEndian.AppendUint32(w.b, math.Float32bits(float32(v)))
+		return
+	}
+	w.b = append(w.b, 0xfb)
+	w.b = binary.BigEndian.AppendUint64(w.b, math.Float64bits(v))
+}
+
+func (w *cborWriter) writeString(v string) {
+	w.writeHead(3, uint64(len(v)))
+	w.b = append(w.b, v...)
+}
+
+func (w *cborWriter) writeBytes(v []byte) {
+	w.writeHead(2, uint64(len(v)))
+	w.b = append(w.b, v...)
+}
+
+func (w *cborWriter) writeArrayHeader(n int) {
+	w.writeHead(4, uint64(n))
+}
+
+func (w *cborWriter) writeMapHeader(n int) {
+	w.writeHead(5, uint64(n))
+}
+
+func (w *cborWriter) writeTime(v time.Time) {
+	w.writeHead(6, 0)
+	w.writeString(v.Format(time.RFC3339Nano))
+}
+
+// cborBreak is returned internally when the break marker ending an indefinite length item is read.
+var cborBreak = errors.New("data: unexpected CBOR break")
+
+// cborDecoder is used to read CBOR.
+type cborDecoder struct {
+	b   []byte
+	pos int
+}
+
+// next is used to read the next n bytes.
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.b)-d.pos) {
+		return nil, errUnexpectedEOF
+	}
+	b := d.b[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// head is used to read the head of a item, returning the major type, additional information and argument.
+func (d *cborDecoder) head() (byte, byte, uint64, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	Major, Info := b[0]>>5, b[0]&0x1f
+	switch {
+	case Info < 24:
+		return Major, Info, uint64(Info), nil
+	case Info == 24:
+		b, err = d.next(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return Major, Info, uint64(b[0]), nil
+	case Info == 25:
+		b, err = d.next(2)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return Major, Info, uint64(binary.BigEndian.Uint16(b)), nil
+	case Info == 26:
+		b, err = d.next(4)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return Major, Info, uint64(binary.BigEndian.Uint32(b)), nil
+	case Info == 27:
+		b, err = d.next(8)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return Major, Info, binary.BigEndian.Uint64(b), nil
+	case Info == 31:
+		return Major, Info, 0, nil
+	}
+	return 0, 0, 0, errors.New("data: invalid CBOR additional information " + strconv.Itoa(int(Info)))
+}
+
+// length is used to check that a definite length fits in the rest of the document. Each item takes at least the
+// number of bytes specified.
+func (d *cborDecoder) length(n uint64, Size uint64) (int, error) {
+	if n > uint64(len(d.b)-d.pos)/Size {
+		return 0, errUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+// str is used to read a byte or text string, joining the chunks of indefinite length strings.
+func (d *cborDecoder) str(Major byte, Info byte, n uint64) ([]byte, error) {
+	if Info != 31 {
+		return d.next(n)
+	}
+	var b []byte
+	for {
+		ChunkMajor, ChunkInfo, Chunk, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if ChunkMajor == 7 && ChunkInfo == 31 {
+			return b, nil
+		}
+		if ChunkMajor != Major || ChunkInfo == 31 {
+			return nil, errors.New("data: invalid chunk in indefinite length CBOR string")
+		}
+		s, err := d.next(Chunk)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, s...)
+	}
+}
+
+// value is used to read the next value.
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errTooDeep
+	}
+	Major, Info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if Info == 31 && (Major < 2 || Major == 6) {
+		return nil, errors.New("data: invalid indefinite length CBOR item")
+	}
+	switch Major {
+	case 0:
+		return integer(n, false), nil
+	case 1:
+		return integer(n, true), nil
+	case 2:
+		b, err := d.str(Major, Info, n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 3:
+		b, err := d.str(Major, Info, n)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, errors.New("data: invalid UTF-8 in CBOR text string")
+		}
+		return string(b), nil
+	case 4:
+		var Items []interface{}
+		if Info == 31 {
+			Items = []interface{}{}
+			for {
+				v, err := d.value(depth + 1)
+				if err == cborBreak {
+					return Items, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				Items = append(Items, v)
+			}
+		}
+		l, err := d.length(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		Items = make([]interface{}, l)
+		for i := range Items {
+			if Items[i], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return Items, nil
+	case 5:
+		var Keys, Values []interface{}
+		l := -1
+		if Info != 31 {
+			if l, err = d.length(n, 2); err != nil {
+				return nil, err
+			}
+			Keys, Values = make([]interface{}, 0, l), make([]interface{}, 0, l)
+		}
+		for i := 0; l == -1 || i < l; i++ {
+			k, err := d.value(depth + 1)
+			if err == cborBreak && l == -1 {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			Keys, Values = append(Keys, k), append(Values, v)
+		}
+		return newMap(Keys, Values)
+	case 6:
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag(n, v)
+	}
+
+	// Major type 7 holds simple values and floats.
+	switch Info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return halfToFloat(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	case 31:
+		return nil, cborBreak
+	}
+	return nil, errors.New("data: unsupported CBOR simple value " + strconv.FormatUint(n, 10))
+}
+
+// cborTag is used to handle a tagged value. Times and bignums which fit in 64 bits are converted, and other tags
+// are ignored.
+func cborTag(Tag uint64, v interface{}) (interface{}, error) {
+	switch Tag {
+	case 0:
+		if s, ok := v.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, errors.New("data: invalid CBOR date/time string")
+	case 1:
+		switch x := v.(type) {
+		case int64:
+			return time.Unix(x, 0).UTC(), nil
+		case uint64:
+			return time.Unix(int64(x), 0).UTC(), nil
+		case float64:
+			Seconds, Fraction := math.Modf(x)
+			return time.Unix(int64(Seconds), int64(Fraction*1e9)).UTC(), nil
+		}
+		return nil, errors.New("data: invalid CBOR epoch time")
+	case 2, 3:
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, errors.New("data: invalid CBOR bignum")
+		}
+		for len(b) > 0 && b[0] == 0 {
+			b = b[1:]
+		}
+		if len(b) > 8 {
+			return nil, errors.New("data: CBOR bignum does not fit in 64 bits")
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return integer(n, Tag == 3), nil
+	}
+	return v, nil
+}
+
+// halfToFloat is used to convert a IEEE 754 half precision float.
+func halfToFloat(h uint16) float64 {
+	Exponent, Mantissa := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch Exponent {
+	case 0:
+		f = math.Ldexp(Mantissa, -24)
+	case 31:
+		if Mantissa == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(Mantissa+1024, Exponent-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
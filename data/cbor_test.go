@@ -0,0 +1,174 @@
+package data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cborTestItem struct {
+	Name    string            `cbor:"name"`
+	Count   int               `json:"count"`
+	Tags    []string          `cbor:"tags,omitempty"`
+	Extra   map[string]uint16 `cbor:"extra"`
+	Skipped string            `cbor:"-"`
+}
+
+func TestCBORVectors(t *testing.T) {
+	// Test vectors from RFC 8949 appendix A.
+	vectors := []struct {
+		hex   string
+		value interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"20", int64(-1)},
+		{"3863", int64(-100)},
+		{"f93e00", 1.5},
+		{"f97bff", 65504.0},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"62c3bc", "ü"},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"a26161016162820203", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c11a514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c249010000000000000000", nil},
+	}
+	for _, v := range vectors {
+		b, _ := hex.DecodeString(v.hex)
+		var value interface{}
+		err := UnmarshalCBOR(b, &value)
+		if v.value == nil && v.hex != "f6" {
+			if err == nil {
+				t.Error("Expected an error decoding", v.hex)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(v.hex, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(value, v.value) {
+			t.Errorf("Decoding %s returned %#v, expected %#v.", v.hex, value, v.value)
+		}
+	}
+}
+
+func TestCBOREncoding(t *testing.T) {
+	vectors := []struct {
+		value interface{}
+		hex   string
+	}{
+		{0, "00"},
+		{uint8(24), "1818"},
+		{1000000, "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-100, "3863"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{float32(100000), "fa47c35000"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]interface{}{"b": []int{2, 3}, "a": 1}, "a26161016162820203"},
+	}
+	for _, v := range vectors {
+		b, err := MarshalCBOR(v.value)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+		if hex.EncodeToString(b) != v.hex {
+			t.Errorf("Encoding %#v returned %x, expected %s.", v.value, b, v.hex)
+		}
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	item := cborTestItem{
+		Name:    "hello",
+		Count:   -5,
+		Extra:   map[string]uint16{"a": 1},
+		Skipped: "skipped",
+	}
+	b, err := MarshalCBOR(&item)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var decoded cborTestItem
+	if err = UnmarshalCBOR(b, &decoded); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	item.Skipped = ""
+	if !reflect.DeepEqual(item, decoded) {
+		t.Errorf("Round trip returned %#v.", decoded)
+		return
+	}
+
+	var generic map[string]interface{}
+	if err = UnmarshalCBOR(b, &generic); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if _, ok := generic["tags"]; ok {
+		t.Error("Expected the empty tags to be omitted.")
+	}
+
+	when := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	b, err = MarshalCBOR(when)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var decodedTime time.Time
+	if err = UnmarshalCBOR(b, &decodedTime); err != nil || !decodedTime.Equal(when) {
+		t.Error("Invalid time returned", decodedTime, err)
+	}
+}
+
+func TestCBORInvalid(t *testing.T) {
+	for _, v := range []string{"", "18", "9b00000000ffffffff", "62c3", "0000", "ff", "1c"} {
+		b, _ := hex.DecodeString(v)
+		var value interface{}
+		if err := UnmarshalCBOR(b, &value); err == nil {
+			t.Error("Expected an error decoding", v)
+		}
+	}
+
+	var small uint8
+	if err := UnmarshalCBOR([]byte{0x19, 0x01, 0x00}, &small); err == nil {
+		t.Error("Expected an overflow error.")
+	}
+
+	if err := UnmarshalCBOR([]byte{0x00}, small); err == nil {
+		t.Error("Expected an error decoding into a non-pointer.")
+	}
+
+	deep := append(bytes.Repeat([]byte{0x81}, maxDepth+2), 0x00)
+	var value interface{}
+	if err := UnmarshalCBOR(deep, &value); err != errTooDeep {
+		t.Error("Expected the document to be too deep, got", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Package data contains matched Serializers and Parsers for structuredhttp. Serializers are passed to
+// Request.Serialize and Parsers are passed to Response.Parse:
+//
+//	res, err := structuredhttp.POST(URL).Serialize(&body, data.CBORSerializer).Run()
+//	...
+//	value, err := res.Parse(data.CBORParser)
+//
+// Importing the package also registers its parsers with structuredhttp.RegisterParser, so Response.Decode can decode
+// their media types. Like the rest of the library, everything is implemented without third party dependencies.
+package data
+
+import (
+	"encoding/json"
+	"encoding/xml"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// JSONSerializer serializes request bodies as JSON.
+var JSONSerializer = structuredhttp.Serializer{
+	Encode:      json.Marshal,
+	ContentType: "application/json",
+}
+
+// JSONParser parses JSON response bodies.
+var JSONParser structuredhttp.Parser = json.Unmarshal
+
+// XMLSerializer serializes request bodies as XML.
+var XMLSerializer = structuredhttp.Serializer{
+	Encode:      xml.Marshal,
+	ContentType: "application/xml",
+}
+
+// XMLParser parses XML response bodies. See UnmarshalXML for how documents are decoded into interfaces.
+var XMLParser structuredhttp.Parser = UnmarshalXML
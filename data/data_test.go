@@ -0,0 +1,120 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+type formTestItem struct {
+	Name    string   `form:"name"`
+	Age     int      `json:"age"`
+	Admin   bool     `form:"admin"`
+	Tags    []string `form:"tag"`
+	Ignored string   `form:"-"`
+	Empty   string   `form:"empty,omitempty"`
+}
+
+func TestForm(t *testing.T) {
+	item := formTestItem{Name: "a b", Age: 30, Admin: true, Tags: []string{"x", "y"}, Ignored: "no"}
+	b, err := MarshalForm(&item)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if string(b) != "admin=true&age=30&name=a+b&tag=x&tag=y" {
+		t.Error("Invalid form returned (" + string(b) + ").")
+		return
+	}
+
+	var decoded formTestItem
+	if err = UnmarshalForm(b, &decoded); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	item.Ignored = ""
+	if !reflect.DeepEqual(item, decoded) {
+		t.Errorf("Round trip returned %#v.", decoded)
+		return
+	}
+
+	var values url.Values
+	if err = UnmarshalForm([]byte("a=1&a=2"), &values); err != nil || values.Get("a") != "1" {
+		t.Error("Failed to decode into url.Values.", err)
+		return
+	}
+
+	var generic interface{}
+	if err = UnmarshalForm([]byte("a=1&a=2&b=3"), &generic); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	expected := map[string]interface{}{"a": []interface{}{"1", "2"}, "b": "3"}
+	if !reflect.DeepEqual(generic, expected) {
+		t.Errorf("Invalid generic value returned %#v.", generic)
+	}
+
+	if err = UnmarshalForm([]byte("age=abc"), &decoded); err == nil {
+		t.Error("Expected an error decoding a invalid number.")
+	}
+
+	// Nested slices cannot be encoded, but byte slices in a slice can.
+	var unsupported *UnsupportedTypeError
+	if _, err = MarshalForm(map[string]interface{}{"a": [][]string{{"x"}}}); !errors.As(err, &unsupported) {
+		t.Error("Expected a *UnsupportedTypeError for a nested slice, got", err)
+	}
+	if b, err = MarshalForm(map[string]interface{}{"a": [][]byte{[]byte("x"), []byte("y")}}); err != nil || string(b) != "a=x&a=y" {
+		t.Error("Invalid form for byte slices", string(b), err)
+	}
+}
+
+func TestXML(t *testing.T) {
+	var generic interface{}
+	err := XMLParser([]byte(`<?xml version="1.0"?><user id="1"><name>hello</name><tag>a</tag><tag>b</tag></user>`), &generic)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	expected := map[string]interface{}{
+		"user": map[string]interface{}{"@id": "1", "name": "hello", "tag": []interface{}{"a", "b"}},
+	}
+	if !reflect.DeepEqual(generic, expected) {
+		t.Errorf("Invalid generic value returned %#v.", generic)
+		return
+	}
+
+	type user struct {
+		Name string `xml:"name"`
+	}
+	b, err := XMLSerializer.Encode(user{Name: "hello"})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var decoded user
+	if err = XMLParser(b, &decoded); err != nil || decoded.Name != "hello" {
+		t.Error("Failed to round trip XML.", err)
+	}
+}
+
+func TestRegisteredParsers(t *testing.T) {
+	b, err := MarshalCBOR(map[string]string{"hello": "world"})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	response := &structuredhttp.Response{RawResponse: &http.Response{
+		Header: http.Header{"Content-Type": []string{"application/cbor"}},
+		Body:   ioutil.NopCloser(bytes.NewReader(b)),
+	}}
+	var value map[string]string
+	if err = response.Decode(&value); err != nil || value["hello"] != "world" {
+		t.Error("Failed to decode CBOR.", err)
+	}
+}
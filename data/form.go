@@ -0,0 +1,159 @@
+package data
+
+import (
+	"encoding"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// FormSerializer serializes request bodies as URL encoded forms.
+var FormSerializer = structuredhttp.Serializer{
+	Encode:      MarshalForm,
+	ContentType: "application/x-www-form-urlencoded",
+}
+
+// FormParser parses URL encoded form response bodies.
+var FormParser structuredhttp.Parser = UnmarshalForm
+
+// MarshalForm encodes url.Values, maps with string keys and structs as a URL encoded form. Struct fields are named
+// with the form tag, falling back to the json tag. Slices and arrays become repeated keys.
+func MarshalForm(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case url.Values:
+		return []byte(x.Encode()), nil
+	case *url.Values:
+		return []byte(x.Encode()), nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	Values := url.Values{}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, &UnsupportedTypeError{Type: rv.Type()}
+		}
+		for _, k := range rv.MapKeys() {
+			s, err := formValues(rv.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			Values[k.String()] = s
+		}
+	case reflect.Struct:
+		for _, f := range structFields(rv.Type(), "form") {
+			Field := rv.FieldByIndex(f.index)
+			if f.omitEmpty && isEmpty(Field) {
+				continue
+			}
+			s, err := formValues(Field)
+			if err != nil {
+				return nil, err
+			}
+			Values[f.name] = s
+		}
+	default:
+		if !rv.IsValid() {
+			return []byte{}, nil
+		}
+		return nil, &UnsupportedTypeError{Type: rv.Type()}
+	}
+	return []byte(Values.Encode()), nil
+}
+
+// formValues is used to convert a value into the strings for its key.
+func formValues(v reflect.Value) ([]string, error) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			Item, err := formValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, Item)
+		}
+		return s, nil
+	}
+	Item, err := formValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return []string{Item}, nil
+}
+
+// formValue is used to convert a single value into a string.
+func formValue(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return "", nil
+		}
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "", nil
+		}
+		return formValue(v.Elem())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice, reflect.Array:
+		// Only byte slices can be a single value. Nested slices cannot be encoded.
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return "", &UnsupportedTypeError{Type: v.Type()}
+		}
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return string(b), nil
+	}
+	return "", &UnsupportedTypeError{Type: v.Type()}
+}
+
+// UnmarshalForm decodes a URL encoded form into the Go value pointed to by into. This can be a *url.Values, a map or
+// a struct. Struct fields are matched with the form tag, falling back to the json tag. Strings are converted into
+// the type of the field, and repeated keys can be decoded into slices. When decoding into an interface, keys with
+// one value become strings and repeated keys become a []interface{}.
+func UnmarshalForm(b []byte, into interface{}) error {
+	Values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	if Pointer, ok := into.(*url.Values); ok {
+		*Pointer = Values
+		return nil
+	}
+	m := make(map[string]interface{}, len(Values))
+	for k, v := range Values {
+		if len(v) == 1 {
+			m[k] = v[0]
+			continue
+		}
+		Items := make([]interface{}, len(v))
+		for i, s := range v {
+			Items[i] = s
+		}
+		m[k] = Items
+	}
+	return assigner{tag: "form", weak: true}.decodeInto(m, into)
+}
@@ -0,0 +1,373 @@
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// MessagePackSerializer serializes request bodies as MessagePack.
+var MessagePackSerializer = structuredhttp.Serializer{
+	Encode:      MarshalMessagePack,
+	ContentType: "application/msgpack",
+}
+
+// MessagePackParser parses MessagePack response bodies.
+var MessagePackParser structuredhttp.Parser = UnmarshalMessagePack
+
+// MarshalMessagePack encodes a Go value as MessagePack. Struct fields are named with the msgpack tag, falling back to
+// the json tag, and times are encoded with the timestamp extension type.
+func MarshalMessagePack(v interface{}) ([]byte, error) {
+	w := &msgpackWriter{}
+	if err := encode(w, reflect.ValueOf(v), "msgpack", 0); err != nil {
+		return nil, err
+	}
+	return w.b, nil
+}
+
+// UnmarshalMessagePack decodes MessagePack into the Go value pointed to by into. When decoding into an interface,
+// maps with string keys become map[string]interface{}, integers become int64 (or uint64 if they do not fit), and
+// timestamps become time.Time.
+func UnmarshalMessagePack(b []byte, into interface{}) error {
+	d := &msgpackDecoder{b: b}
+	Value, err := d.value(0)
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.b) {
+		return errors.New("data: trailing data after MessagePack value")
+	}
+	return assigner{tag: "msgpack"}.decodeInto(Value, into)
+}
+
+// msgpackWriter is used to write MessagePack.
+type msgpackWriter struct {
+	b []byte
+}
+
+// writeLength is used to write a length with the smallest of the 8, 16 and 32-bit formats specified. A code of 0
+// means there is no 8-bit format.
+func (w *msgpackWriter) writeLength(n int, Code8, Code16, Code32 byte) {
+	switch {
+	case Code8 != 0 && n <= math.MaxUint8:
+		w.b = append(w.b, Code8, byte(n))
+	case n <= math.MaxUint16:
+		w.b = append(w.b, Code16)
+		w.b = binary.BigEndian.AppendUint16(w.b, uint16(n))
+	default:
+		w.b = append(w.b, Code32)
+		w.b = binary.BigEndian.AppendUint32(w.b, uint32(n))
+	}
+}
+
+func (w *msgpackWriter) writeNil() {
+	w.b = append(w.b, 0xc0)
+}
+
+func (w *msgpackWriter) writeBool(v bool) {
+	if v {
+		w.b = append(w.b, 0xc3)
+	} else {
+		w.b = append(w.b, 0xc2)
+	}
+}
+
+func (w *msgpackWriter) writeInt(v int64) {
+	switch {
+	case v >= 0:
+		w.writeUint(uint64(v))
+	case v >= -32:
+		w.b = append(w.b, byte(v))
+	case v >= math.MinInt8:
+		w.b = append(w.b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		w.b = append(w.b, 0xd1)
+		w.b = binary.BigEndian.AppendUint16(w.b, uint16(v))
+	case v >= math.MinInt32:
+		w.b = append(w.b, 0xd2)
+		w.b = binary.BigEndian.AppendUint32(w.b, uint32(v))
+	default:
+		w.b = append(w.b, 0xd3)
+		w.b = binary.BigEndian.AppendUint64(w.b, uint64(v))
+	}
+}
+
+func (w *msgpackWriter) writeUint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		w.b = append(w.b, byte(v))
+	case v <= math.MaxUint8:
+		w.b = append(w.b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		w.b = append(w.b, 0xcd)
+		w.b = binary.BigEndian.AppendUint16(w.b, uint16(v))
+	case v <= math.MaxUint32:
+		w.b = append(w.b, 0xce)
+		w.b = binary.BigEndian.AppendUint32(w.b, uint32(v))
+	default:
+		w.b = append(w.b, 0xcf)
+		w.b = binary.BigEndian.AppendUint64(w.b, v)
+	}
+}
+
+func (w *msgpackWriter) writeFloat(v float64, bits int) {
+	if bits == 32 {
+		w.b = append(w.b, 0xca)
+		w.b = binary.BigEndian.AppendUint32(w.b, math.Float32bits(float32(v)))
+		return
+	}
+	w.b = append(w.b, 0xcb)
+	w.b = binary.BigEndian.AppendUint64(w.b, math.Float64bits(v))
+}
+
+func (w *msgpackWriter) writeString(v string) {
+	if len(v) < 32 {
+		w.b = append(w.b, 0xa0|byte(len(v)))
+	} else {
+		w.writeLength(len(v), 0xd9, 0xda, 0xdb)
+	}
+	w.b = append(w.b, v...)
+}
+
+func (w *msgpackWriter) writeBytes(v []byte) {
+	w.writeLength(len(v), 0xc4, 0xc5, 0xc6)
+	w.b = append(w.b, v...)
+}
+
+func (w *msgpackWriter) writeArrayHeader(n int) {
+	if n < 16 {
+		w.b = append(w.b, 0x90|byte(n))
+		return
+	}
+	w.writeLength(n, 0, 0xdc, 0xdd)
+}
+
+func (w *msgpackWriter) writeMapHeader(n int) {
+	if n < 16 {
+		w.b = append(w.b, 0x80|byte(n))
+		return
+	}
+	w.writeLength(n, 0, 0xde, 0xdf)
+}
+
+func (w *msgpackWriter) writeTime(v time.Time) {
+	Seconds, Nanoseconds := v.Unix(), uint64(v.Nanosecond())
+	switch {
+	case Seconds >= 0 && Seconds <= math.MaxUint32 && Nanoseconds == 0:
+		w.b = append(w.b, 0xd6, 0xff)
+		w.b = binary.BigEndian.AppendUint32(w.b, uint32(Seconds))
+	case Seconds >= 0 && Seconds < 1<<34:
+		w.b = append(w.b, 0xd7, 0xff)
+		w.b = binary.BigEndian.AppendUint64(w.b, Nanoseconds<<34|uint64(Seconds))
+	default:
+		w.b = append(w.b, 0xc7, 12, 0xff)
+		w.b = binary.BigEndian.AppendUint32(w.b, uint32(Nanoseconds))
+		w.b = binary.BigEndian.AppendUint64(w.b, uint64(Seconds))
+	}
+}
+
+// msgpackDecoder is used to read MessagePack.
+type msgpackDecoder struct {
+	b   []byte
+	pos int
+}
+
+// next is used to read the next n bytes.
+func (d *msgpackDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.b)-d.pos) {
+		return nil, errUnexpectedEOF
+	}
+	b := d.b[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// uint is used to read a big endian unsigned integer of the size specified.
+func (d *msgpackDecoder) uint(Size int) (uint64, error) {
+	b, err := d.next(uint64(Size))
+	if err != nil {
+		return 0, err
+	}
+	switch Size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// value is used to read the next value.
+func (d *msgpackDecoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errTooDeep
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	Code := b[0]
+	switch {
+	case Code <= 0x7f:
+		return int64(Code), nil
+	case Code >= 0xe0:
+		return int64(int8(Code)), nil
+	case Code <= 0x8f:
+		return d.mapValue(uint64(Code&0x0f), depth)
+	case Code <= 0x9f:
+		return d.array(uint64(Code&0x0f), depth)
+	case Code <= 0xbf:
+		return d.str(uint64(Code & 0x1f))
+	}
+
+	switch Code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (Code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (Code - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(n)
+	case 0xca:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 0xcb:
+		n, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(n), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (Code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return integer(n, false), nil
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (Code - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (Code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (Code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (Code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(n, depth)
+	}
+	return nil, errors.New("data: invalid MessagePack format code " + strconv.Itoa(int(Code)))
+}
+
+// str is used to read a string of the length specified.
+func (d *msgpackDecoder) str(n uint64) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// array is used to read an array of the length specified.
+func (d *msgpackDecoder) array(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.b)-d.pos) {
+		return nil, errUnexpectedEOF
+	}
+	Items := make([]interface{}, n)
+	for i := range Items {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		Items[i] = v
+	}
+	return Items, nil
+}
+
+// mapValue is used to read a map of the length specified.
+func (d *msgpackDecoder) mapValue(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.b)-d.pos)/2 {
+		return nil, errUnexpectedEOF
+	}
+	Keys, Values := make([]interface{}, n), make([]interface{}, n)
+	for i := range Keys {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		Keys[i], Values[i] = k, v
+	}
+	return newMap(Keys, Values)
+}
+
+// ext is used to read an extension value with data of the length specified. Only the timestamp extension is
+// supported.
+func (d *msgpackDecoder) ext(n uint64) (interface{}, error) {
+	b, err := d.next(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	Type, Data := int8(b[0]), b[1:]
+	if Type != -1 {
+		return nil, errors.New("data: unsupported MessagePack extension type " + strconv.Itoa(int(Type)))
+	}
+	switch len(Data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(Data)), 0).UTC(), nil
+	case 8:
+		n := binary.BigEndian.Uint64(Data)
+		return time.Unix(int64(n&(1<<34-1)), int64(n>>34)).UTC(), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(Data[4:])), int64(binary.BigEndian.Uint32(Data))).UTC(), nil
+	}
+	return nil, errors.New("data: invalid MessagePack timestamp length " + strconv.Itoa(len(Data)))
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type msgpackTestItem struct {
+	ID      uint64           `msgpack:"id"`
+	Name    string           `msgpack:"name"`
+	Score   float32          `msgpack:"score"`
+	Nested  *msgpackTestItem `msgpack:"nested,omitempty"`
+	Created time.Time        `msgpack:"created"`
+	Raw     []byte           `msgpack:"raw"`
+}
+
+func TestMessagePackEncoding(t *testing.T) {
+	vectors := []struct {
+		value interface{}
+		hex   string
+	}{
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{65535, "cdffff"},
+		{65536, "ce00010000"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-129, "d1ff7f"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{1.5, "cb3ff8000000000000"},
+		{float32(1.5), "ca3fc00000"},
+		{nil, "c0"},
+		{false, "c2"},
+		{true, "c3"},
+		{"abc", "a3616263"},
+		{strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]int{1, 2}, "920102"},
+		{map[string]int{"a": 1}, "81a16101"},
+		{time.Unix(1, 0), "d6ff00000001"},
+		{time.Unix(1, 1), "d7ff0000000400000001"},
+	}
+	for _, v := range vectors {
+		b, err := MarshalMessagePack(v.value)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+		if hex.EncodeToString(b) != v.hex {
+			t.Errorf("Encoding %#v returned %x, expected %s.", v.value, b, v.hex)
+			continue
+		}
+		var value interface{}
+		if err = UnmarshalMessagePack(b, &value); err != nil {
+			t.Error(v.hex, err.Error())
+		}
+	}
+}
+
+func TestMessagePackRoundTrip(t *testing.T) {
+	item := msgpackTestItem{
+		ID:      math.MaxUint64,
+		Name:    strings.Repeat("x", 70000),
+		Score:   2.5,
+		Nested:  &msgpackTestItem{ID: 2, Created: time.Unix(-100, 5).UTC()},
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		Raw:     []byte{0, 1, 2},
+	}
+	b, err := MarshalMessagePack(item)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var decoded msgpackTestItem
+	if err = UnmarshalMessagePack(b, &decoded); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if !reflect.DeepEqual(item, decoded) {
+		t.Error("Round trip returned a different item.")
+		return
+	}
+
+	var generic interface{}
+	if err = UnmarshalMessagePack(b, &generic); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	m := generic.(map[string]interface{})
+	if m["id"] != uint64(math.MaxUint64) || m["score"] != 2.5 || m["nested"].(map[string]interface{})["id"] != int64(2) {
+		t.Errorf("Invalid generic value returned %#v.", m["nested"])
+	}
+}
+
+func TestMessagePackInvalid(t *testing.T) {
+	for _, v := range []string{"", "c1", "cc", "a2", "dd0000ffff", "d4010000", "0000"} {
+		b, _ := hex.DecodeString(v)
+		var value interface{}
+		if err := UnmarshalMessagePack(b, &value); err == nil {
+			t.Error("Expected an error decoding", v)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package data
+
+import "github.com/jakemakesstuff/structuredhttp"
+
+// init registers the parsers in this package for Response.Decode.
+func init() {
+	structuredhttp.RegisterParser("application/xml", UnmarshalXML)
+	structuredhttp.RegisterParser("text/xml", UnmarshalXML)
+	structuredhttp.RegisterParser("+xml", UnmarshalXML)
+	structuredhttp.RegisterParser("application/x-www-form-urlencoded", UnmarshalForm)
+	structuredhttp.RegisterParser("application/cbor", UnmarshalCBOR)
+	structuredhttp.RegisterParser("+cbor", UnmarshalCBOR)
+	structuredhttp.RegisterParser("application/msgpack", UnmarshalMessagePack)
+	structuredhttp.RegisterParser("application/x-msgpack", UnmarshalMessagePack)
+	structuredhttp.RegisterParser("application/vnd.msgpack", UnmarshalMessagePack)
+}
@@ -0,0 +1,648 @@
+package data
+
+import (
+	"encoding"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDepth is the maximum nesting of arrays and maps which will be decoded.
+const maxDepth = 1000
+
+// errTooDeep is returned when a document is nested deeper than maxDepth.
+var errTooDeep = errors.New("data: document is nested too deeply")
+
+// errUnexpectedEOF is returned when a binary document ends part way through a value.
+var errUnexpectedEOF = errors.New("data: unexpected end of document")
+
+// UnsupportedTypeError is returned when a Go value cannot be encoded.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+// Error implements the error interface.
+func (e *UnsupportedTypeError) Error() string {
+	return "data: unsupported type " + e.Type.String()
+}
+
+// TypeError is returned when a decoded value cannot be stored in the Go value specified.
+type TypeError struct {
+	Value string
+	Type  reflect.Type
+}
+
+// Error implements the error interface.
+func (e *TypeError) Error() string {
+	return "data: cannot decode " + e.Value + " into a value of type " + e.Type.String()
+}
+
+// InvalidDecodeError is returned when the value to decode into is not a non-nil pointer.
+type InvalidDecodeError struct {
+	Type reflect.Type
+}
+
+// Error implements the error interface.
+func (e *InvalidDecodeError) Error() string {
+	if e.Type == nil {
+		return "data: decode into nil"
+	}
+	return "data: decode into non-pointer or nil pointer of type " + e.Type.String()
+}
+
+// writer is implemented by the binary formats to write the values found when walking a Go value.
+type writer interface {
+	writeNil()
+	writeBool(v bool)
+	writeInt(v int64)
+	writeUint(v uint64)
+	writeFloat(v float64, bits int)
+	writeString(v string)
+	writeBytes(v []byte)
+	writeArrayHeader(n int)
+	writeMapHeader(n int)
+}
+
+// timeWriter is implemented by writers for formats which have a native representation of time. Formats which do
+// not have one encode time as RFC 3339 text.
+type timeWriter interface {
+	writeTime(v time.Time)
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// field defines a struct field which is encoded.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// fieldCache caches the fields of struct types for each tag name.
+var fieldCache sync.Map
+
+// fieldCacheKey is the key used for fieldCache.
+type fieldCacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// structFields is used to get the fields of a struct. The tag specified is used for the name and options, falling
+// back to the json tag if it is not set.
+func structFields(t reflect.Type, tag string) []field {
+	if f, ok := fieldCache.Load(fieldCacheKey{t, tag}); ok {
+		return f.([]field)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		Tag, ok := f.Tag.Lookup(tag)
+		if !ok {
+			Tag = f.Tag.Get("json")
+		}
+		if Tag == "-" {
+			continue
+		}
+		Name, Options := Tag, ""
+		if i := strings.Index(Tag, ","); i != -1 {
+			Name, Options = Tag[:i], Tag[i+1:]
+		}
+
+		// Flatten embedded structs which are not named.
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && Name == "" && ft.Kind() == reflect.Struct {
+			if f.Type.Kind() == reflect.Ptr {
+				// Embedded pointers are skipped since they may be nil.
+				continue
+			}
+			for _, v := range structFields(ft, tag) {
+				v.index = append([]int{i}, v.index...)
+				fields = append(fields, v)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if Name == "" {
+			Name = f.Name
+		}
+		fields = append(fields, field{
+			name:      Name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+Options+",", ",omitempty,"),
+		})
+	}
+	fieldCache.Store(fieldCacheKey{t, tag}, fields)
+	return fields
+}
+
+// isEmpty is used to check if a value is empty for omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// encode is used to walk a Go value and write it with the writer specified. Struct fields are named with the tag
+// specified.
+func encode(w writer, v reflect.Value, tag string, depth int) error {
+	if depth > maxDepth {
+		return errTooDeep
+	}
+	if !v.IsValid() {
+		w.writeNil()
+		return nil
+	}
+
+	// Handle time if the format has a native representation of it.
+	if v.Type() == timeType {
+		if tw, ok := w.(timeWriter); ok {
+			tw.writeTime(v.Interface().(time.Time))
+			return nil
+		}
+	}
+
+	// Handle values which marshal themselves to text.
+	if v.Type().Implements(textMarshalerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		w.writeString(string(b))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		return encode(w, v.Elem(), tag, depth+1)
+	case reflect.Bool:
+		w.writeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.writeUint(v.Uint())
+	case reflect.Float32:
+		w.writeFloat(v.Float(), 32)
+	case reflect.Float64:
+		w.writeFloat(v.Float(), 64)
+	case reflect.String:
+		w.writeString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() == reflect.Slice {
+				if v.IsNil() {
+					w.writeNil()
+					return nil
+				}
+				w.writeBytes(v.Bytes())
+				return nil
+			}
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			w.writeBytes(b)
+			return nil
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		w.writeArrayHeader(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := encode(w, v.Index(i), tag, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		Keys := v.MapKeys()
+		if v.Type().Key().Kind() == reflect.String {
+			// Sort string keys so the output is deterministic.
+			sort.Slice(Keys, func(i, j int) bool { return Keys[i].String() < Keys[j].String() })
+		}
+		w.writeMapHeader(len(Keys))
+		for _, k := range Keys {
+			if err := encode(w, k, tag, depth+1); err != nil {
+				return err
+			}
+			if err := encode(w, v.MapIndex(k), tag, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var Fields []field
+		for _, f := range structFields(v.Type(), tag) {
+			if !f.omitEmpty || !isEmpty(v.FieldByIndex(f.index)) {
+				Fields = append(Fields, f)
+			}
+		}
+		w.writeMapHeader(len(Fields))
+		for _, f := range Fields {
+			w.writeString(f.name)
+			if err := encode(w, v.FieldByIndex(f.index), tag, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+// describe is used to describe a decoded value for errors.
+func describe(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64, uint64:
+		return "integer"
+	case float64:
+		return "float"
+	case string:
+		return "string " + strconv.Quote(x)
+	case []byte:
+		return "byte string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "map"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// newMap is used to create the map which a decoded map is stored in when decoding into an interface. If all the
+// keys are strings, a map[string]interface{} is used like encoding/json.
+func newMap(Keys, Values []interface{}) (interface{}, error) {
+	Strings := true
+	for _, k := range Keys {
+		if _, ok := k.(string); !ok {
+			Strings = false
+			break
+		}
+	}
+	if Strings {
+		m := make(map[string]interface{}, len(Keys))
+		for i, k := range Keys {
+			m[k.(string)] = Values[i]
+		}
+		return m, nil
+	}
+	m := make(map[interface{}]interface{}, len(Keys))
+	for i, k := range Keys {
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, errors.New("data: map key of type " + reflect.TypeOf(k).String() + " is not comparable")
+		}
+		m[k] = Values[i]
+	}
+	return m, nil
+}
+
+// integer is used to normalise a decoded integer, using int64 where it fits.
+func integer(v uint64, Negative bool) interface{} {
+	if Negative {
+		// The value is -1-v, which only fits in an int64 if v fits.
+		if v > math.MaxInt64 {
+			return float64(-1) - float64(v)
+		}
+		return -1 - int64(v)
+	}
+	if v > math.MaxInt64 {
+		return v
+	}
+	return int64(v)
+}
+
+// assigner is used to store decoded values in Go values.
+type assigner struct {
+	// tag is the tag used to match struct fields, falling back to the json tag.
+	tag string
+
+	// weak converts strings into booleans and numbers, and single values into slices. This is used for form data.
+	weak bool
+}
+
+// decodeInto is used to check the value to decode into and then assign the decoded value to it.
+func (a assigner) decodeInto(Value interface{}, into interface{}) error {
+	rv := reflect.ValueOf(into)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecodeError{Type: reflect.TypeOf(into)}
+	}
+	return a.assign(Value, rv.Elem())
+}
+
+// assign is used to store a decoded value in a Go value.
+func (a assigner) assign(Value interface{}, dst reflect.Value) error {
+	// Handle nil by zeroing the destination.
+	if Value == nil {
+		switch dst.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+		return nil
+	}
+
+	// Use the first of repeated values when decoding weakly into a single value.
+	if Items, ok := Value.([]interface{}); ok && a.weak && len(Items) != 0 {
+		switch dst.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Interface, reflect.Ptr:
+		default:
+			Value = Items[0]
+		}
+	}
+
+	// Handle values which unmarshal themselves from text.
+	if dst.Kind() != reflect.Ptr && dst.Kind() != reflect.Interface && dst.CanAddr() &&
+		dst.Addr().Type().Implements(textUnmarshalerType) {
+		switch x := Value.(type) {
+		case string:
+			return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(x))
+		case []byte:
+			return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(x)
+		}
+	}
+
+	// Handle values which can be directly stored.
+	if dst.Kind() != reflect.Interface && reflect.TypeOf(Value) == dst.Type() {
+		dst.Set(reflect.ValueOf(Value))
+		return nil
+	}
+
+	fail := func() error {
+		return &TypeError{Value: describe(Value), Type: dst.Type()}
+	}
+	switch dst.Kind() {
+	case reflect.Interface:
+		rv := reflect.ValueOf(Value)
+		if !rv.Type().AssignableTo(dst.Type()) {
+			return fail()
+		}
+		dst.Set(rv)
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return a.assign(Value, dst.Elem())
+	case reflect.Bool:
+		switch x := Value.(type) {
+		case bool:
+			dst.SetBool(x)
+		case string:
+			b, err := strconv.ParseBool(x)
+			if !a.weak || err != nil {
+				return fail()
+			}
+			dst.SetBool(b)
+		default:
+			return fail()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch x := Value.(type) {
+		case int64:
+			i = x
+		case uint64:
+			if x > math.MaxInt64 {
+				return fail()
+			}
+			i = int64(x)
+		case float64:
+			if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+				return fail()
+			}
+			i = int64(x)
+		case string:
+			var err error
+			if i, err = strconv.ParseInt(x, 10, 64); !a.weak || err != nil {
+				return fail()
+			}
+		default:
+			return fail()
+		}
+		if dst.OverflowInt(i) {
+			return fail()
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch x := Value.(type) {
+		case int64:
+			if x < 0 {
+				return fail()
+			}
+			u = uint64(x)
+		case uint64:
+			u = x
+		case float64:
+			if x != math.Trunc(x) || x < 0 || x >= math.MaxUint64 {
+				return fail()
+			}
+			u = uint64(x)
+		case string:
+			var err error
+			if u, err = strconv.ParseUint(x, 10, 64); !a.weak || err != nil {
+				return fail()
+			}
+		default:
+			return fail()
+		}
+		if dst.OverflowUint(u) {
+			return fail()
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch x := Value.(type) {
+		case int64:
+			f = float64(x)
+		case uint64:
+			f = float64(x)
+		case float64:
+			f = x
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(x, 64); !a.weak || err != nil {
+				return fail()
+			}
+		default:
+			return fail()
+		}
+		if dst.Kind() == reflect.Float32 && dst.OverflowFloat(f) {
+			return fail()
+		}
+		dst.SetFloat(f)
+	case reflect.String:
+		switch x := Value.(type) {
+		case string:
+			dst.SetString(x)
+		case []byte:
+			dst.SetString(string(x))
+		default:
+			return fail()
+		}
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch x := Value.(type) {
+			case []byte:
+				dst.SetBytes(append([]byte(nil), x...))
+				return nil
+			case string:
+				dst.SetBytes([]byte(x))
+				return nil
+			}
+		}
+		Items, ok := Value.([]interface{})
+		if !ok {
+			if !a.weak {
+				return fail()
+			}
+			Items = []interface{}{Value}
+		}
+		s := reflect.MakeSlice(dst.Type(), len(Items), len(Items))
+		for i, v := range Items {
+			if err := a.assign(v, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+	case reflect.Array:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			if b, ok := Value.([]byte); ok {
+				if len(b) > dst.Len() {
+					return fail()
+				}
+				reflect.Copy(dst, reflect.ValueOf(b))
+				return nil
+			}
+		}
+		Items, ok := Value.([]interface{})
+		if !ok || len(Items) > dst.Len() {
+			return fail()
+		}
+		for i, v := range Items {
+			if err := a.assign(v, dst.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		Keys, Values, ok := mapEntries(Value)
+		if !ok {
+			return fail()
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(Keys)))
+		}
+		for i, k := range Keys {
+			kv := reflect.New(dst.Type().Key()).Elem()
+			if err := a.assign(k, kv); err != nil {
+				return err
+			}
+			vv := reflect.New(dst.Type().Elem()).Elem()
+			if err := a.assign(Values[i], vv); err != nil {
+				return err
+			}
+			dst.SetMapIndex(kv, vv)
+		}
+	case reflect.Struct:
+		Keys, Values, ok := mapEntries(Value)
+		if !ok {
+			return fail()
+		}
+		return a.assignStruct(Keys, Values, dst)
+	default:
+		return fail()
+	}
+	return nil
+}
+
+// mapEntries is used to get the keys and values of a decoded map.
+func mapEntries(Value interface{}) ([]interface{}, []interface{}, bool) {
+	switch x := Value.(type) {
+	case map[string]interface{}:
+		Keys := make([]interface{}, 0, len(x))
+		Values := make([]interface{}, 0, len(x))
+		for k, v := range x {
+			Keys = append(Keys, k)
+			Values = append(Values, v)
+		}
+		return Keys, Values, true
+	case map[interface{}]interface{}:
+		Keys := make([]interface{}, 0, len(x))
+		Values := make([]interface{}, 0, len(x))
+		for k, v := range x {
+			Keys = append(Keys, k)
+			Values = append(Values, v)
+		}
+		return Keys, Values, true
+	}
+	return nil, nil, false
+}
+
+// assignStruct is used to store the entries of a decoded map in the fields of a struct. Keys are matched to field
+// names exactly, falling back to a case insensitive match. Keys which do not match a field are ignored.
+func (a assigner) assignStruct(Keys, Values []interface{}, dst reflect.Value) error {
+	Fields := structFields(dst.Type(), a.tag)
+	for i, k := range Keys {
+		Name, ok := k.(string)
+		if !ok {
+			continue
+		}
+		var Match *field
+		for j := range Fields {
+			if Fields[j].name == Name {
+				Match = &Fields[j]
+				break
+			}
+			if Match == nil && strings.EqualFold(Fields[j].name, Name) {
+				Match = &Fields[j]
+			}
+		}
+		if Match == nil {
+			continue
+		}
+		if err := a.assign(Values[i], dst.FieldByIndex(Match.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// UnmarshalXML decodes XML into the Go value pointed to by into. If into points to an interface, the document is
+// decoded into a map[string]interface{} with the root element name as the only key. Elements with no attributes or
+// children become strings. Other elements become maps where attributes are keyed by "@" and their name, text is
+// keyed by "#text", and children are keyed by their name, becoming a []interface{} if the name repeats. Otherwise,
+// this is the same as xml.Unmarshal.
+func UnmarshalXML(b []byte, into interface{}) error {
+	Pointer, ok := into.(*interface{})
+	if !ok {
+		return xml.Unmarshal(b, into)
+	}
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		Token, err := d.Token()
+		if err == io.EOF {
+			return errors.New("data: no root element in XML document")
+		}
+		if err != nil {
+			return err
+		}
+		if Start, ok := Token.(xml.StartElement); ok {
+			Value, err := xmlElement(d, Start, 0)
+			if err != nil {
+				return err
+			}
+			*Pointer = map[string]interface{}{Start.Name.Local: Value}
+			return nil
+		}
+	}
+}
+
+// xmlElement is used to decode the contents of an element after its start token has been read.
+func xmlElement(d *xml.Decoder, Start xml.StartElement, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errTooDeep
+	}
+	m := map[string]interface{}{}
+	for _, v := range Start.Attr {
+		m["@"+v.Name.Local] = v.Value
+	}
+	Text := strings.Builder{}
+	for {
+		Token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch x := Token.(type) {
+		case xml.StartElement:
+			Child, err := xmlElement(d, x, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			switch Existing := m[x.Name.Local].(type) {
+			case nil:
+				m[x.Name.Local] = Child
+			case []interface{}:
+				m[x.Name.Local] = append(Existing, Child)
+			default:
+				m[x.Name.Local] = []interface{}{Existing, Child}
+			}
+		case xml.CharData:
+			Text.Write(x)
+		case xml.EndElement:
+			s := strings.TrimSpace(Text.String())
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m["#text"] = s
+			}
+			return m, nil
+		}
+	}
+}
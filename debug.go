@@ -0,0 +1,139 @@
+package structuredhttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactedHeaders are the headers which usually hold secrets, so are redacted by Curl.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+// shellQuote is used to quote a string for a POSIX shell. Strings with control characters or invalid UTF-8 use
+// ANSI-C quoting, which is supported by bash and zsh.
+func shellQuote(s string) string {
+	Plain := utf8.ValidString(s)
+	for i := 0; Plain && i < len(s); i++ {
+		if s[i] < 0x20 && s[i] != '\n' && s[i] != '\t' || s[i] == 0x7f {
+			Plain = false
+		}
+	}
+	if Plain {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	b := strings.Builder{}
+	b.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			b.WriteString(`\x`)
+			b.WriteString(strconv.FormatUint(uint64(c)>>4, 16))
+			b.WriteString(strconv.FormatUint(uint64(c)&0xf, 16))
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// sortedHeaders is used to get the header keys of the request in order.
+func (r *Request) sortedHeaders() []string {
+	Keys := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		Keys = append(Keys, k)
+	}
+	sort.Strings(Keys)
+	return Keys
+}
+
+// Curl returns the request as a curl command. If Redact is true, the values of headers which usually hold secrets
+// are replaced. Bodies from readers which cannot be replayed are buffered into memory, so the request can still be
+// ran after.
+func (r *Request) Curl(Redact bool) (string, error) {
+	if r.Error != nil {
+		return "", *r.Error
+	}
+	Body, err := r.BodyBytes()
+	if err != nil {
+		return "", err
+	}
+	Parts := []string{"curl"}
+	switch {
+	case r.Method == "HEAD":
+		Parts = append(Parts, "--head")
+	case r.Method != "GET" || len(Body) != 0:
+		Parts = append(Parts, "-X", r.Method)
+	}
+	for _, k := range r.sortedHeaders() {
+		v := r.Headers[k]
+		if k == "Content-Length" {
+			// curl sets this itself from the body.
+			continue
+		}
+		if Redact && redactedHeaders[http.CanonicalHeaderKey(k)] {
+			v = "REDACTED"
+		}
+		if v == "" {
+			// curl removes headers without a value, so empty headers end with a semicolon instead.
+			Parts = append(Parts, "-H", shellQuote(k+";"))
+			continue
+		}
+		Parts = append(Parts, "-H", shellQuote(k+": "+v))
+	}
+	if r.CurrentTimeout != nil && *r.CurrentTimeout != 0 {
+		Parts = append(Parts, "--max-time", strconv.FormatFloat(r.CurrentTimeout.Seconds(), 'f', -1, 64))
+	}
+	if len(Body) != 0 {
+		Parts = append(Parts, "--data-binary", shellQuote(string(Body)))
+	}
+	Parts = append(Parts, shellQuote(r.URL))
+	return strings.Join(Parts, " "), nil
+}
+
+// Dump returns the request as it would be written on the wire with HTTP/1.1. Bodies from readers which cannot be
+// replayed are buffered into memory, so the request can still be ran after.
+func (r *Request) Dump() ([]byte, error) {
+	if r.Error != nil {
+		return nil, *r.Error
+	}
+	Body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	RawRequest, err := http.NewRequest(r.Method, r.URL, bytes.NewReader(Body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.Headers {
+		RawRequest.Header.Set(k, v)
+	}
+	if len(Body) == 0 {
+		RawRequest.Body = http.NoBody
+		RawRequest.ContentLength = 0
+	}
+	b := bytes.Buffer{}
+	if err = RawRequest.Write(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Dump returns the response as it was received with HTTP/1.1. The body is read into memory and replaced, so it can
+// still be read after.
+func (r *Response) Dump() ([]byte, error) {
+	return httputil.DumpResponse(r.RawResponse, true)
+}
@@ -0,0 +1,75 @@
+package structuredhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCurl(t *testing.T) {
+	req := POST("https://example.com/users").Header("Authorization", "Bearer secret").Timeout(
+		1500 * time.Millisecond).JSON(map[string]string{"name": "it's"})
+	command, err := req.Curl(true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	expected := `curl -X POST -H 'Authorization: REDACTED' -H 'Content-Type: application/json' --max-time 1.5 ` +
+		`--data-binary '{"name":"it'\''s"}' 'https://example.com/users'`
+	if command != expected {
+		t.Error("Invalid command returned (" + command + ").")
+		return
+	}
+
+	command, err = GET("https://example.com").Reader(strings.NewReader("\x00")).Curl(false)
+	if err != nil || command != `curl -X GET --data-binary $'\x00' 'https://example.com'` {
+		t.Error("Invalid command returned ("+command+").", err)
+		return
+	}
+	t.Log("Curl works!")
+}
+
+func TestDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Test", "yes")
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+
+	// Use a one-shot reader to check it is still sent after being dumped.
+	req := POST(server.URL+"/echo").Header("X-Test", "yes").Reader(ioutil.NopCloser(strings.NewReader("hello")))
+	dump, err := req.Dump()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if !strings.HasPrefix(string(dump), "POST /echo HTTP/1.1\r\nHost: ") ||
+		!strings.Contains(string(dump), "\r\nX-Test: yes\r\n") || !strings.HasSuffix(string(dump), "\r\n\r\nhello") {
+		t.Error("Invalid request dump returned (" + string(dump) + ").")
+		return
+	}
+
+	response, err := req.Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	dump, err = response.Dump()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if !strings.HasPrefix(string(dump), "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(string(dump), "\r\n\r\nhello") {
+		t.Error("Invalid response dump returned (" + string(dump) + ").")
+		return
+	}
+	if text, _ := response.Text(); text != "hello" {
+		t.Error("Expected the body to still be readable.")
+		return
+	}
+	t.Log("Dump works!")
+}
@@ -0,0 +1,167 @@
+package structuredhttp
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// UnsupportedMediaTypeError is returned by Decode when there is no parser registered for the media type of the
+// response.
+type UnsupportedMediaTypeError struct {
+	MediaType string
+}
+
+// Error implements the error interface.
+func (e *UnsupportedMediaTypeError) Error() string {
+	if e.MediaType == "" {
+		return "the response has no Content-Type to decode it with"
+	}
+	return "there is no parser registered for the media type " + e.MediaType
+}
+
+// UnsupportedCharsetError is returned by Decode when the response body is in a charset which cannot be converted to
+// UTF-8.
+type UnsupportedCharsetError struct {
+	Charset string
+}
+
+// Error implements the error interface.
+func (e *UnsupportedCharsetError) Error() string {
+	return "the charset " + e.Charset + " is not supported"
+}
+
+var (
+	parsers     = map[string]Parser{}
+	parsersLock = sync.RWMutex{}
+)
+
+func init() {
+	RegisterParser("application/json", json.Unmarshal)
+	RegisterParser("+json", json.Unmarshal)
+	RegisterParser("application/xml", xml.Unmarshal)
+	RegisterParser("text/xml", xml.Unmarshal)
+	RegisterParser("+xml", xml.Unmarshal)
+	RegisterParser("text/*", parseText)
+}
+
+// RegisterParser registers the parser which is used by Decode for a media type. The media type can be a full type
+// such as "application/json", a structured syntax suffix such as "+json", or a wildcard such as "text/*". Full types
+// are used first, then suffixes, then wildcards. Registering a nil parser removes the media type.
+func RegisterParser(MediaType string, parser Parser) {
+	MediaType = strings.ToLower(MediaType)
+	parsersLock.Lock()
+	if parser == nil {
+		delete(parsers, MediaType)
+	} else {
+		parsers[MediaType] = parser
+	}
+	parsersLock.Unlock()
+}
+
+// lookupParser is used to find the parser for a media type.
+func lookupParser(MediaType string) Parser {
+	parsersLock.RLock()
+	defer parsersLock.RUnlock()
+	if p, ok := parsers[MediaType]; ok {
+		return p
+	}
+	if i := strings.LastIndex(MediaType, "+"); i != -1 {
+		if p, ok := parsers[MediaType[i:]]; ok {
+			return p
+		}
+	}
+	if i := strings.Index(MediaType, "/"); i != -1 {
+		if p, ok := parsers[MediaType[:i]+"/*"]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// parseText is used to parse text into a *string, *[]byte, *interface{} or encoding.TextUnmarshaler.
+func parseText(b []byte, into interface{}) error {
+	switch x := into.(type) {
+	case *string:
+		*x = string(b)
+	case *[]byte:
+		*x = b
+	case *interface{}:
+		*x = string(b)
+	case encoding.TextUnmarshaler:
+		return x.UnmarshalText(b)
+	default:
+		return errors.New("cannot decode text into a value of type " + reflect.TypeOf(into).String())
+	}
+	return nil
+}
+
+// toUTF8 is used to convert a body in the charset specified to UTF-8.
+func toUTF8(b []byte, Charset string) ([]byte, error) {
+	switch strings.ToLower(Charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return b, nil
+	case "iso-8859-1", "latin1", "l1":
+		Converted := make([]byte, 0, len(b))
+		for _, c := range b {
+			Converted = utf8.AppendRune(Converted, rune(c))
+		}
+		return Converted, nil
+	case "utf-16", "utf-16be", "utf-16le":
+		if len(b)%2 != 0 {
+			return nil, errors.New("the body is not valid UTF-16")
+		}
+		LittleEndian := strings.EqualFold(Charset, "utf-16le")
+		if len(b) >= 2 && strings.EqualFold(Charset, "utf-16") {
+			// Use the byte order mark if there is one. Without one, big endian is used.
+			if b[0] == 0xff && b[1] == 0xfe {
+				LittleEndian, b = true, b[2:]
+			} else if b[0] == 0xfe && b[1] == 0xff {
+				b = b[2:]
+			}
+		}
+		Units := make([]uint16, len(b)/2)
+		for i := range Units {
+			if LittleEndian {
+				Units[i] = uint16(b[i*2]) | uint16(b[i*2+1])<<8
+			} else {
+				Units[i] = uint16(b[i*2])<<8 | uint16(b[i*2+1])
+			}
+		}
+		return []byte(string(utf16.Decode(Units))), nil
+	}
+	return nil, &UnsupportedCharsetError{Charset: Charset}
+}
+
+// Decode parses the response body into the pointer specified, using the parser registered for the media type in the
+// Content-Type header. If the Content-Type has a charset, the body is converted to UTF-8 first. If there is no parser
+// for the media type, the error is a *UnsupportedMediaTypeError.
+func (r *Response) Decode(into interface{}) error {
+	Header := r.RawResponse.Header.Get("Content-Type")
+	if Header == "" {
+		return &UnsupportedMediaTypeError{}
+	}
+	MediaType, Params, err := mime.ParseMediaType(Header)
+	if err != nil {
+		return err
+	}
+	parser := lookupParser(MediaType)
+	if parser == nil {
+		return &UnsupportedMediaTypeError{MediaType: MediaType}
+	}
+	b, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+	if b, err = toUTF8(b, Params["charset"]); err != nil {
+		return err
+	}
+	return parser(b, into)
+}
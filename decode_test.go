@@ -0,0 +1,60 @@
+package structuredhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"title": "Not Found"}`))
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/plain; charset=ISO-8859-1")
+			_, _ = w.Write([]byte{'c', 'a', 'f', 0xe9})
+		default:
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+	}))
+	defer server.Close()
+
+	response, err := GET(server.URL + "/problem").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var problem struct {
+		Title string `json:"title"`
+	}
+	if err = response.Decode(&problem); err != nil || problem.Title != "Not Found" {
+		t.Error("Failed to decode JSON.", err)
+		return
+	}
+
+	response, err = GET(server.URL + "/latin1").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var text string
+	if err = response.Decode(&text); err != nil || text != "café" {
+		t.Error("Failed to decode text ("+text+").", err)
+		return
+	}
+
+	response, err = GET(server.URL + "/binary").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var unsupported *UnsupportedMediaTypeError
+	if err = response.Decode(&text); !errors.As(err, &unsupported) || unsupported.MediaType != "application/octet-stream" {
+		t.Error("Expected a *UnsupportedMediaTypeError, got", err)
+		return
+	}
+	t.Log("Decode works!")
+}
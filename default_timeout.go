@@ -2,7 +2,8 @@ package structuredhttp
 
 import "time"
 
-// DefaultTimeout defines the default timeout.
+// DefaultTimeout defines the default timeout. This is global, so use a Client with a Timeout to configure it
+// for a subset of requests.
 var DefaultTimeout = time.Duration(0)
 
 // SetDefaultTimeout allows the user to set the default timeout in this library.
@@ -0,0 +1,117 @@
+package structuredhttp
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dialer opens the connection for a request. Address is the host and port from the URL, or of the proxy if one is
+// used.
+type Dialer func(ctx context.Context, Network, Address string) (net.Conn, error)
+
+// dialerContextKey is the context key for the dialer of a request.
+type dialerContextKey struct{}
+
+// defaultDialer is the dialer used when none is set, with the same options as http.DefaultTransport.
+var defaultDialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+// dialFromContext is used as the DialContext function of transports. The dialer set on the request or client is used
+// if there is one.
+func dialFromContext(ctx context.Context, Network, Address string) (net.Conn, error) {
+	if Dial, ok := ctx.Value(dialerContextKey{}).(Dialer); ok {
+		return Dial(ctx, Network, Address)
+	}
+	return defaultDialer.DialContext(ctx, Network, Address)
+}
+
+// contextTransport is the transport used for requests which need a proxy or dialer but have no transport of their
+// own.
+var contextTransport struct {
+	once      sync.Once
+	transport *http.Transport
+}
+
+// newContextTransport is used to create a transport which uses the proxy and dialer from the request context.
+func newContextTransport() *http.Transport {
+	Transport := http.DefaultTransport.(*http.Transport).Clone()
+	Transport.Proxy = proxyFromContext
+	Transport.DialContext = dialFromContext
+	return Transport
+}
+
+// defaultContextTransport is used to get the shared transport for requests which need a proxy or dialer but have no
+// transport.
+func defaultContextTransport() *http.Transport {
+	contextTransport.once.Do(func() {
+		contextTransport.transport = newContextTransport()
+	})
+	return contextTransport.transport
+}
+
+// withTransportOptions is used to add the proxy and dialer of the request to the context. True is returned if
+// either was added.
+func (r *Request) withTransportOptions(ctx context.Context) (context.Context, bool) {
+	Dial := r.CurrentDialer
+	if Dial == nil && r.CurrentClient != nil {
+		Dial = r.CurrentClient.Dialer
+	}
+	ctx, Proxied := r.withProxy(ctx)
+	if Dial == nil {
+		return ctx, Proxied
+	}
+	return context.WithValue(ctx, dialerContextKey{}, Dial), true
+}
+
+// UnixDialer returns a dialer which connects to the Unix socket specified, whatever the address of the request.
+// Paths starting with @ are in the Linux abstract namespace. When using it with the Dial chain step, also call
+// Proxy("") so the request is not sent to a proxy from the environment.
+func UnixDialer(Path string) Dialer {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return defaultDialer.DialContext(ctx, "unix", Path)
+	}
+}
+
+// Dial sets the dialer which opens the connection for this request, overriding the dialer of the client. Connections
+// are pooled by host, so requests to the same host with different dialers should use different clients. This has no
+// effect on requests made with a client whose transport is not from NewClient, or on WASM.
+func (r *Request) Dial(Dialer Dialer) *Request {
+	if r.Error != nil {
+		return r
+	}
+	r.CurrentDialer = Dialer
+	return r
+}
+
+// unixSocket is used to get the socket path of a unix:///path or unix:@name URL.
+func unixSocket(u *url.URL) (string, bool) {
+	if u.Scheme != "unix" {
+		return "", false
+	}
+	if u.Opaque != "" {
+		return u.Opaque, true
+	}
+	return unixPath(u.Path), true
+}
+
+// unixPath is used to get the socket path from the path of a unix:// URL, where /@name is an abstract socket.
+func unixPath(Path string) string {
+	if strings.HasPrefix(Path, "/@") {
+		return Path[1:]
+	}
+	return Path
+}
+
+// unixHost is used to get a host name which is unique to a socket, so connections to different sockets are not
+// pooled together.
+func unixHost(Path string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(Path))
+	return "unix-" + strconv.FormatUint(h.Sum64(), 16) + ".localhost"
+}
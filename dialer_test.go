@@ -0,0 +1,118 @@
+package structuredhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+// unixServer starts a server on the Unix socket specified which returns the socket and path.
+func unixServer(t *testing.T, path string) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(path + " " + r.Host + " " + r.URL.Path))
+		})},
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+	sockets := []string{filepath.Join(dir, "a.sock"), filepath.Join(dir, "b.sock")}
+	urls := []string{"unix://" + sockets[0], "unix:" + sockets[1]}
+	if runtime.GOOS == "linux" {
+		sockets = append(sockets, "@structuredhttp-test")
+		urls = append(urls, "unix:@structuredhttp-test")
+	}
+	for _, socket := range sockets {
+		unixServer(t, socket)
+	}
+
+	// Each socket gets its own requests, even through the same client.
+	client := NewClient()
+	for i, socket := range sockets {
+		handler := RouteHandler{BaseURL: urls[i], Client: client}
+		for j := 0; j < 2; j++ {
+			response, err := handler.GET("/v1/info").Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text, _ := response.Text(); text != socket+" localhost /v1/info" {
+				t.Error("Unexpected response from", socket, text)
+			}
+		}
+	}
+}
+
+func TestDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Send requests for a made up host to the test server.
+	var dials int32
+	dialer := func(ctx context.Context, network, address string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		if address != "api.test:80" {
+			t.Error("Unexpected address", address)
+		}
+		return net.Dial(network, server.Listener.Addr().String())
+	}
+	client := NewClient()
+	client.Dialer = dialer
+	if _, err := client.GET("http://api.test/").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GET("http://api.test/").Dial(dialer).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if dials != 2 {
+		t.Error("Expected the dialer to be used twice, got", dials)
+	}
+}
+
+func TestUnixSocketIgnoresProxy(t *testing.T) {
+	proxyURL := "http://user:pw@proxy.invalid:3128"
+	t.Setenv("HTTP_PROXY", proxyURL)
+	t.Setenv("http_proxy", proxyURL)
+
+	// The proxy is dialed with the Unix dialer too, so a proxied request reaches the socket in proxy form.
+	socket := filepath.Join(t.TempDir(), "proxy.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.RequestURI != "/v1/ping" || r.Header.Get("Proxy-Authorization") != "" {
+				t.Error("The request was proxied to", r.RequestURI, "with", r.Header.Get("Proxy-Authorization"))
+			}
+		})},
+	}
+	server.Start()
+	defer server.Close()
+	client := NewClient()
+	if err := client.SetProxy(proxyURL); err != nil {
+		t.Fatal(err)
+	}
+	for _, handler := range []RouteHandler{
+		{BaseURL: "unix://" + socket},
+		{BaseURL: "unix://" + socket, Client: client},
+		{BaseURL: "unix://" + socket, Proxy: proxyURL},
+	} {
+		if _, err = handler.GET("/v1/ping").Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
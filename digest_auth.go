@@ -0,0 +1,218 @@
+package structuredhttp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// digestChallenge is a Digest challenge from a WWW-Authenticate header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       bool
+	nc        int
+}
+
+// digestCache holds the last challenge for each host, so later requests can authenticate without another 401.
+type digestCache struct {
+	lock       sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+// digestCacheLock is used to create the Digest challenge cache of route handlers.
+var digestCacheLock sync.Mutex
+
+// digestCache is used to get the Digest challenge cache of the route handler, creating it the first time.
+func (r *RouteHandler) digestCache() *digestCache {
+	digestCacheLock.Lock()
+	defer digestCacheLock.Unlock()
+	if r.digest == nil {
+		r.digest = &digestCache{challenges: map[string]*digestChallenge{}}
+	}
+	return r.digest
+}
+
+// digestHash is used to get the hash function for an algorithm, or nil if it is not supported.
+func digestHash(Algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(Algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	default:
+		return nil
+	}
+}
+
+// parseDigestChallenge is used to get the strongest supported Digest challenge from the WWW-Authenticate headers.
+func parseDigestChallenge(Header http.Header) *digestChallenge {
+	var Best *digestChallenge
+	for _, v := range Header.Values("WWW-Authenticate") {
+		if len(v) < 7 || !strings.EqualFold(v[:7], "Digest ") {
+			continue
+		}
+		Params := map[string]string{}
+		for _, Pair := range splitDigestParams(v[7:]) {
+			i := strings.IndexByte(Pair, '=')
+			if i == -1 {
+				continue
+			}
+			Params[strings.ToLower(strings.TrimSpace(Pair[:i]))] = strings.Trim(strings.TrimSpace(Pair[i+1:]), `"`)
+		}
+		c := &digestChallenge{
+			realm:     Params["realm"],
+			nonce:     Params["nonce"],
+			opaque:    Params["opaque"],
+			algorithm: Params["algorithm"],
+		}
+		for _, q := range strings.Split(Params["qop"], ",") {
+			if strings.TrimSpace(q) == "auth" {
+				c.qop = true
+			}
+		}
+		if c.nonce == "" || digestHash(c.algorithm) == nil || Params["qop"] != "" && !c.qop {
+			continue
+		}
+		if Best == nil || digestHash(Best.algorithm)().Size() < digestHash(c.algorithm)().Size() {
+			Best = c
+		}
+	}
+	return Best
+}
+
+// splitDigestParams is used to split the parameters of a challenge on commas which are not in quotes.
+func splitDigestParams(s string) []string {
+	var Params []string
+	Quoted := false
+	Start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			Quoted = !Quoted
+		case '\\':
+			i++
+		case ',':
+			if !Quoted {
+				Params = append(Params, s[Start:i])
+				Start = i + 1
+			}
+		}
+	}
+	return append(Params, s[Start:])
+}
+
+// authorization is used to create the Authorization header for the challenge, counting the nonce use.
+func (c *digestCache) authorization(Host, Method, URI, Username, Password string) string {
+	c.lock.Lock()
+	Challenge, ok := c.challenges[Host]
+	if !ok {
+		c.lock.Unlock()
+		return ""
+	}
+	Challenge.nc++
+	Current := *Challenge
+	c.lock.Unlock()
+
+	h := func(s string) string {
+		Hash := digestHash(Current.algorithm)()
+		_, _ = io.WriteString(Hash, s)
+		return hex.EncodeToString(Hash.Sum(nil))
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	CNonce := hex.EncodeToString(b)
+	NC := fmt.Sprintf("%08x", Current.nc)
+
+	// Work out the response.
+	HA1 := h(Username + ":" + Current.realm + ":" + Password)
+	if strings.HasSuffix(strings.ToUpper(Current.algorithm), "-SESS") {
+		HA1 = h(HA1 + ":" + Current.nonce + ":" + CNonce)
+	}
+	HA2 := h(Method + ":" + URI)
+	Response := h(HA1 + ":" + Current.nonce + ":" + HA2)
+	if Current.qop {
+		Response = h(HA1 + ":" + Current.nonce + ":" + NC + ":" + CNonce + ":auth:" + HA2)
+	}
+
+	// Create the header.
+	Quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	Header := "Digest username=" + Quote(Username) + ", realm=" + Quote(Current.realm) + ", nonce=" +
+		Quote(Current.nonce) + ", uri=" + Quote(URI) + ", response=" + Quote(Response)
+	if Current.algorithm != "" {
+		Header += ", algorithm=" + Current.algorithm
+	}
+	if Current.opaque != "" {
+		Header += ", opaque=" + Quote(Current.opaque)
+	}
+	if Current.qop {
+		Header += ", qop=auth, nc=" + NC + ", cnonce=" + Quote(CNonce)
+	}
+	return Header
+}
+
+// DigestAuth authenticates the request with HTTP Digest authentication. The first request to a host is sent without
+// credentials, and if the server responds with a Digest challenge, the request is sent again with the body replayed.
+// MD5 and SHA-256 are supported, with or without qop=auth. Requests made with a RouteHandler share the last challenge
+// for each host, so later requests authenticate the first time.
+func (r *Request) DigestAuth(Username, Password string) *Request {
+	if r.Error != nil {
+		return r
+	}
+	Cache := r.digestCache
+	if Cache == nil {
+		Cache = &digestCache{challenges: map[string]*digestChallenge{}}
+	}
+	return r.Use(func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			u, err := url.Parse(r.URL)
+			if err != nil {
+				return nil, err
+			}
+			Body, err := r.replayableBody(true)
+			if err != nil {
+				return nil, err
+			}
+			for Attempt := 1; ; Attempt++ {
+				// Set the body from the start.
+				if r.CurrentReader, err = Body(); err != nil {
+					return nil, err
+				}
+
+				// Send the request with the cached challenge if there is one.
+				if Header := Cache.authorization(u.Host, r.Method, u.RequestURI(), Username, Password); Header != "" {
+					r.Headers["Authorization"] = Header
+				}
+				res, err := next(ctx, r)
+				if err != nil || res.RawResponse.StatusCode != http.StatusUnauthorized || Attempt == 2 {
+					return res, err
+				}
+				Challenge := parseDigestChallenge(res.RawResponse.Header)
+				if Challenge == nil {
+					return res, nil
+				}
+
+				// Drain and close the body so the connection can be reused.
+				_, _ = io.Copy(ioutil.Discard, res.RawResponse.Body)
+				_ = res.RawResponse.Body.Close()
+				Cache.lock.Lock()
+				Cache.challenges[u.Host] = Challenge
+				Cache.lock.Unlock()
+			}
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package structuredhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// digestServer creates a server which requires SHA-256 Digest authentication with qop=auth.
+func digestServer(t *testing.T, nonce *string) (*httptest.Server, *int) {
+	challenges := 0
+	h := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]string{}
+		header := r.Header.Get("Authorization")
+		for _, pair := range splitDigestParams(strings.TrimPrefix(header, "Digest ")) {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = strings.Trim(kv[1], `"`)
+			}
+		}
+		ha1 := h("jake:test:hunter2")
+		ha2 := h(r.Method + ":" + params["uri"])
+		expected := h(ha1 + ":" + *nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if params["nonce"] != *nonce || params["response"] != expected || params["uri"] != r.URL.RequestURI() {
+			challenges++
+			w.Header().Add("WWW-Authenticate", `Digest realm="test", qop="auth", algorithm=MD5, nonce="`+*nonce+`"`)
+			w.Header().Add("WWW-Authenticate", `Digest realm="test", qop="auth,auth-int", algorithm=SHA-256, nonce="`+*nonce+`", opaque="x"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.Copy(w, r.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &challenges
+}
+
+func TestDigestAuth(t *testing.T) {
+	nonce := "first"
+	server, challenges := digestServer(t, &nonce)
+	handler := RouteHandler{BaseURL: server.URL}
+
+	// The first request is challenged and sent again with the body.
+	send := func(body string) {
+		t.Helper()
+		response, err := handler.POST("/upload").Query("a", "1").
+			Reader(ioutil.NopCloser(strings.NewReader(body))).
+			DigestAuth("jake", "hunter2").Run()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if err = response.RaiseForStatus(); err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if text, _ := response.Text(); text != body {
+			t.Error("Expected the body to be replayed, got", text)
+		}
+	}
+	send("hello")
+	if *challenges != 1 {
+		t.Error("Expected one challenge, got", *challenges)
+	}
+
+	// Later requests use the cached nonce.
+	send("world")
+	if *challenges != 1 {
+		t.Error("Expected the cached nonce to be used, got", *challenges, "challenges")
+	}
+
+	// A new nonce is picked up from the next challenge.
+	nonce = "second"
+	send("again")
+	if *challenges != 2 {
+		t.Error("Expected the new nonce to be challenged once, got", *challenges, "challenges")
+	}
+
+	// Wrong credentials return the 401.
+	response, err := handler.GET("/").DigestAuth("jake", "wrong").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("Expected 401, got", response.RawResponse.StatusCode)
+	}
+}
@@ -0,0 +1,472 @@
+package structuredhttp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnsupportedCurlFlagError is returned by FromCurl when the command has a flag which is not supported. Reason is set
+// if the flag is only unsupported with the value it was given.
+type UnsupportedCurlFlagError struct {
+	Flag   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *UnsupportedCurlFlagError) Error() string {
+	s := "the curl flag " + e.Flag + " is not supported"
+	if e.Reason != "" {
+		s += " (" + e.Reason + ")"
+	}
+	return s
+}
+
+// CurlOptions are the options used by FromCurlOptions.
+type CurlOptions struct {
+	// AllowFiles allows the command to read local files with -d @file, --data-urlencode name@file, -F name=@file and
+	// -F name=<file. By default these return a *UnsupportedCurlFlagError, so running a pasted command cannot upload
+	// local files.
+	AllowFiles bool
+}
+
+// readCurlFile is used to read a file referenced by a flag, if the options allow it.
+func (o CurlOptions) readCurlFile(Flag, Path string) ([]byte, error) {
+	if !o.AllowFiles {
+		return nil, &UnsupportedCurlFlagError{Flag: Flag, Reason: "reading the file " + Path + " needs AllowFiles"}
+	}
+	return ioutil.ReadFile(Path)
+}
+
+// curlFlags maps the supported long curl flags to their short form, or to themselves if they have none.
+var curlFlags = map[string]string{
+	"--request":        "-X",
+	"--header":         "-H",
+	"--data":           "-d",
+	"--data-ascii":     "-d",
+	"--data-raw":       "--data-raw",
+	"--data-binary":    "--data-binary",
+	"--data-urlencode": "--data-urlencode",
+	"--form":           "-F",
+	"--user":           "-u",
+	"--compressed":     "--compressed",
+	"--insecure":       "-k",
+	"--max-time":       "-m",
+	"--get":            "-G",
+	"--head":           "-I",
+	"--location":       "-L",
+	"--user-agent":     "-A",
+	"--referer":        "-e",
+	"--cookie":         "-b",
+	"--url":            "--url",
+	"--silent":         "-s",
+	"--show-error":     "-S",
+	"--verbose":        "-v",
+	"--include":        "-i",
+}
+
+// curlFlagsWithValues are the flags which take a value.
+var curlFlagsWithValues = map[string]bool{
+	"-X": true, "-H": true, "-d": true, "--data-raw": true, "--data-binary": true, "--data-urlencode": true,
+	"-F": true, "-u": true, "-m": true, "-A": true, "-e": true, "-b": true, "--url": true,
+}
+
+// splitShell is used to split a command into words like a POSIX shell, handling quotes, escapes, line continuations
+// and ANSI-C quoting.
+func splitShell(Command string) ([]string, error) {
+	var Words []string
+	Word := strings.Builder{}
+	InWord := false
+	for i := 0; i < len(Command); i++ {
+		c := Command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if InWord {
+				Words = append(Words, Word.String())
+				Word.Reset()
+				InWord = false
+			}
+		case c == '\\':
+			i++
+			if i == len(Command) {
+				return nil, errors.New("the command ends with a backslash")
+			}
+			if Command[i] == '\n' {
+				continue
+			}
+			if Command[i] == '\r' && i+1 < len(Command) && Command[i+1] == '\n' {
+				i++
+				continue
+			}
+			Word.WriteByte(Command[i])
+			InWord = true
+		case c == '\'':
+			End := strings.IndexByte(Command[i+1:], '\'')
+			if End == -1 {
+				return nil, errors.New("the command has an unterminated single quote")
+			}
+			Word.WriteString(Command[i+1 : i+1+End])
+			i += End + 1
+			InWord = true
+		case c == '$' && i+1 < len(Command) && Command[i+1] == '\'':
+			n, err := ansiQuote(Command[i+2:], &Word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			InWord = true
+		case c == '"':
+			i++
+			for ; i < len(Command) && Command[i] != '"'; i++ {
+				if Command[i] == '\\' && i+1 < len(Command) && strings.IndexByte("\"\\$`\n", Command[i+1]) != -1 {
+					i++
+					if Command[i] == '\n' {
+						continue
+					}
+				}
+				Word.WriteByte(Command[i])
+			}
+			if i == len(Command) {
+				return nil, errors.New("the command has an unterminated double quote")
+			}
+			InWord = true
+		default:
+			Word.WriteByte(c)
+			InWord = true
+		}
+	}
+	if InWord {
+		Words = append(Words, Word.String())
+	}
+	return Words, nil
+}
+
+// ansiQuote is used to read the contents of a $'...' string into the builder, returning the number of bytes read
+// including the closing quote.
+func ansiQuote(s string, Word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return i + 1, nil
+		case '\\':
+			i++
+			if i == len(s) {
+				break
+			}
+			switch s[i] {
+			case 'n':
+				Word.WriteByte('\n')
+			case 't':
+				Word.WriteByte('\t')
+			case 'r':
+				Word.WriteByte('\r')
+			case 'x':
+				End := i + 1
+				for End < len(s) && End < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[End]) != -1 {
+					End++
+				}
+				n, err := strconv.ParseUint(s[i+1:End], 16, 8)
+				if err != nil {
+					return 0, errors.New("the command has an invalid hex escape")
+				}
+				Word.WriteByte(byte(n))
+				i = End - 1
+			default:
+				Word.WriteByte(s[i])
+			}
+		default:
+			Word.WriteByte(s[i])
+		}
+	}
+	return 0, errors.New("the command has an unterminated $' quote")
+}
+
+// curlData is used to read the value of a data flag.
+func (o CurlOptions) curlData(Flag string, Value string) (string, error) {
+	switch Flag {
+	case "--data-raw":
+		return Value, nil
+	case "--data-urlencode":
+		Name, Content := "", Value
+		if i := strings.IndexAny(Value, "=@"); i != -1 {
+			Name, Content = Value[:i], Value[i+1:]
+			if Value[i] == '@' {
+				b, err := o.readCurlFile(Flag, Content)
+				if err != nil {
+					return "", err
+				}
+				Content = string(b)
+			}
+		}
+		if Name == "" {
+			return url.QueryEscape(Content), nil
+		}
+		return Name + "=" + url.QueryEscape(Content), nil
+	}
+	if !strings.HasPrefix(Value, "@") {
+		return Value, nil
+	}
+	if Value == "@-" {
+		return "", errors.New("reading data from stdin is not supported")
+	}
+	b, err := o.readCurlFile(Flag, Value[1:])
+	if err != nil {
+		return "", err
+	}
+	if Flag == "-d" {
+		// Like curl, carriage returns and newlines are removed from files read with --data.
+		b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r"), nil), []byte("\n"), nil)
+	}
+	return string(b), nil
+}
+
+// curlFormPart is used to write a -F form part.
+func (o CurlOptions) curlFormPart(w *multipart.Writer, Value string) error {
+	i := strings.IndexByte(Value, '=')
+	if i == -1 {
+		return errors.New("the form part " + Value + " has no =")
+	}
+	Name, Content := Value[:i], Value[i+1:]
+	ContentType := ""
+	if j := strings.Index(Content, ";type="); j != -1 {
+		Content, ContentType = Content[:j], Content[j+6:]
+	}
+	switch {
+	case strings.HasPrefix(Content, "@"):
+		b, err := o.readCurlFile("-F", Content[1:])
+		if err != nil {
+			return err
+		}
+		if ContentType == "" {
+			ContentType = "application/octet-stream"
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="`+Name+`"; filename="`+filepath.Base(Content[1:])+`"`)
+		h.Set("Content-Type", ContentType)
+		Part, err := w.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		_, err = Part.Write(b)
+		return err
+	case strings.HasPrefix(Content, "<"):
+		b, err := o.readCurlFile("-F", Content[1:])
+		if err != nil {
+			return err
+		}
+		Content = string(b)
+	}
+	if ContentType == "" {
+		return w.WriteField(Name, Content)
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="`+Name+`"`)
+	h.Set("Content-Type", ContentType)
+	Part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = Part.Write([]byte(Content))
+	return err
+}
+
+// FromCurl parses a curl command into a request. The flags -X, -H, -d, --data-raw, --data-binary, --data-urlencode,
+// -F, -u, -k, -m (--max-time), -G, -I, -A, -e and -b are supported, along with their long forms. --compressed and -L
+// are accepted since Go already does both, and output flags such as -s and -v are ignored. Any other flag returns a
+// *UnsupportedCurlFlagError. Flags which read local files are not supported; use FromCurlOptions to allow them.
+func FromCurl(Command string) (*Request, error) {
+	return FromCurlOptions(Command, CurlOptions{})
+}
+
+// FromCurlOptions parses a curl command into a request with the options specified. See FromCurl.
+func FromCurlOptions(Command string, Options CurlOptions) (*Request, error) {
+	Words, err := splitShell(Command)
+	if err != nil {
+		return nil, err
+	}
+	if len(Words) != 0 && Words[0] == "curl" {
+		Words = Words[1:]
+	}
+
+	// Split the words into flags and the URL.
+	type curlFlag struct {
+		Name  string
+		Value string
+	}
+	var Flags []curlFlag
+	URL := ""
+	for i := 0; i < len(Words); i++ {
+		Word := Words[i]
+		var Names []string
+		Value := ""
+		switch {
+		case strings.HasPrefix(Word, "--"):
+			Short, ok := curlFlags[Word]
+			if !ok {
+				return nil, &UnsupportedCurlFlagError{Flag: Word}
+			}
+			Names = []string{Short}
+		case strings.HasPrefix(Word, "-") && len(Word) > 1:
+			// Short flags can be combined (-sSL), and the last can have its value attached (-XPOST).
+			for j := 1; j < len(Word); j++ {
+				Name := "-" + string(Word[j])
+				if !curlFlagsWithValues[Name] && !isShortCurlFlag(Name) {
+					return nil, &UnsupportedCurlFlagError{Flag: Name}
+				}
+				Names = append(Names, Name)
+				if curlFlagsWithValues[Name] {
+					Value = Word[j+1:]
+					break
+				}
+			}
+		default:
+			if URL != "" {
+				return nil, errors.New("the command has more than one URL")
+			}
+			URL = Word
+			continue
+		}
+		Last := Names[len(Names)-1]
+		if curlFlagsWithValues[Last] && Value == "" {
+			i++
+			if i == len(Words) {
+				return nil, errors.New("the curl flag " + Word + " needs a value")
+			}
+			Value = Words[i]
+		}
+		for _, Name := range Names {
+			if Name == "--url" {
+				if URL != "" {
+					return nil, errors.New("the command has more than one URL")
+				}
+				URL = Value
+				continue
+			}
+			Flags = append(Flags, curlFlag{Name: Name, Value: Value})
+		}
+	}
+	if URL == "" {
+		return nil, errors.New("the command has no URL")
+	}
+	if !strings.Contains(URL, "://") {
+		URL = "http://" + URL
+	}
+
+	// Build the request from the flags.
+	r := &Request{URL: URL, Headers: map[string]string{}}
+	var Data []string
+	var Form *multipart.Writer
+	FormBody := &bytes.Buffer{}
+	Get := false
+	for _, f := range Flags {
+		switch f.Name {
+		case "-X":
+			r.Method = f.Value
+		case "-H":
+			i := strings.IndexAny(f.Value, ":;")
+			if i == -1 {
+				return nil, errors.New("the header " + f.Value + " is invalid")
+			}
+			// Like curl, "Name:" removes the header and "Name;" sends it empty.
+			Name, Value := http.CanonicalHeaderKey(strings.TrimSpace(f.Value[:i])), strings.TrimSpace(f.Value[i+1:])
+			if f.Value[i] == ':' && Value != "" {
+				r.Headers[Name] = Value
+			} else if f.Value[i] == ';' && Value == "" {
+				r.Headers[Name] = ""
+			}
+		case "-d", "--data-raw", "--data-binary", "--data-urlencode":
+			Value, err := Options.curlData(f.Name, f.Value)
+			if err != nil {
+				return nil, err
+			}
+			Data = append(Data, Value)
+		case "-F":
+			if Form == nil {
+				Form = multipart.NewWriter(FormBody)
+			}
+			if err := Options.curlFormPart(Form, f.Value); err != nil {
+				return nil, err
+			}
+		case "-u":
+			r.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(f.Value))
+		case "-k":
+			Client := NewClient()
+			Client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+			r.CurrentClient = Client
+		case "-m":
+			Seconds, err := strconv.ParseFloat(f.Value, 64)
+			if err != nil {
+				return nil, errors.New("the max time " + f.Value + " is invalid")
+			}
+			r.Timeout(time.Duration(Seconds * float64(time.Second)))
+		case "-G":
+			Get = true
+		case "-I":
+			r.Method = "HEAD"
+		case "-A":
+			r.Headers["User-Agent"] = f.Value
+		case "-e":
+			r.Headers["Referer"] = f.Value
+		case "-b":
+			if !strings.Contains(f.Value, "=") {
+				return nil, errors.New("reading cookies from a file is not supported")
+			}
+			r.Headers["Cookie"] = f.Value
+		}
+	}
+
+	// Set the body.
+	switch {
+	case Form != nil && Data != nil:
+		return nil, errors.New("the command cannot have both data and a form")
+	case Form != nil:
+		if err := Form.Close(); err != nil {
+			return nil, err
+		}
+		r.MultipartForm(FormBody, Form.FormDataContentType())
+	case Get:
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.RawQuery != "" {
+			Data = append([]string{u.RawQuery}, Data...)
+		}
+		u.RawQuery = strings.Join(Data, "&")
+		r.URL = u.String()
+		if r.Method == "" {
+			r.Method = "GET"
+		}
+	case Data != nil:
+		if r.Headers["Content-Type"] == "" {
+			r.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+		r.Bytes([]byte(strings.Join(Data, "&")))
+	}
+	if r.Method == "" {
+		r.Method = "GET"
+		if Form != nil || Data != nil {
+			r.Method = "POST"
+		}
+	}
+	return r, nil
+}
+
+// isShortCurlFlag is used to check if a short flag without a value is supported.
+func isShortCurlFlag(Name string) bool {
+	switch Name {
+	case "-k", "-G", "-I", "-L", "-s", "-S", "-v", "-i":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package structuredhttp
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromCurl(t *testing.T) {
+	req, err := FromCurl(`curl -sSL -XPUT 'https://example.com/users?a=1' \
+		-H "Content-Type: application/json" -H 'X-Quote: it'\''s' \
+		--data-raw '{"name": "a"}' -u user:pass --max-time 2.5 --compressed -k`)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if req.Method != "PUT" || req.URL != "https://example.com/users?a=1" {
+		t.Error("Invalid method or URL (" + req.Method + " " + req.URL + ").")
+		return
+	}
+	if req.Headers["Content-Type"] != "application/json" || req.Headers["X-Quote"] != "it's" ||
+		req.Headers["Authorization"] != "Basic dXNlcjpwYXNz" {
+		t.Error("Invalid headers", req.Headers)
+		return
+	}
+	if *req.CurrentTimeout != 2500*time.Millisecond || req.CurrentClient == nil {
+		t.Error("Invalid timeout or client.")
+		return
+	}
+	if b, _ := req.BodyBytes(); string(b) != `{"name": "a"}` {
+		t.Error("Invalid body (" + string(b) + ").")
+		return
+	}
+
+	req, err = FromCurl(`curl example.com -G -d a=1 --data-urlencode 'b=c d'`)
+	if err != nil || req.Method != "GET" || req.URL != "http://example.com?a=1&b=c+d" {
+		t.Error("Invalid GET request", req, err)
+		return
+	}
+
+	req, err = FromCurl(`curl -F name=hello -F 'data=<-;type=text/plain' https://example.com`)
+	if err == nil {
+		t.Error("Expected reading a form part from a missing file to fail.")
+		return
+	}
+	req, err = FromCurl(`curl -F name=hello https://example.com`)
+	if err != nil || req.Method != "POST" || !strings.HasPrefix(req.Headers["Content-Type"], "multipart/form-data") {
+		t.Error("Invalid form request", err)
+		return
+	}
+	if b, _ := ioutil.ReadAll(req.CurrentReader); !strings.Contains(string(b), "hello") {
+		t.Error("Invalid form body.")
+		return
+	}
+
+	var unsupported *UnsupportedCurlFlagError
+	if _, err = FromCurl(`curl -o out.txt https://example.com`); !errors.As(err, &unsupported) || unsupported.Flag != "-o" {
+		t.Error("Expected a *UnsupportedCurlFlagError, got", err)
+		return
+	}
+	t.Log("FromCurl works!")
+}
+
+func TestFromCurlRoundTrip(t *testing.T) {
+	original := POST("https://example.com/a b").Header("X-Test", "it's").Timeout(time.Second).Bytes([]byte("\x00\x01'"))
+	command, err := original.Curl(false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	req, err := FromCurl(command)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	b, _ := req.BodyBytes()
+	if req.Method != "POST" || req.URL != original.URL || req.Headers["X-Test"] != "it's" || string(b) != "\x00\x01'" {
+		t.Error("The request did not round trip (" + command + ").")
+		return
+	}
+	t.Log("Curl commands round trip.")
+}
+
+func TestFromCurlFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	_ = ioutil.WriteFile(path, []byte("secret\n"), 0600)
+	commands := []string{
+		`curl -d @` + path + ` https://example.com`,
+		`curl --data-urlencode a@` + path + ` https://example.com`,
+		`curl -F a=@` + path + ` https://example.com`,
+		`curl -F 'a=<` + path + `' https://example.com`,
+	}
+	for _, command := range commands {
+		// Files are not read by default.
+		var unsupported *UnsupportedCurlFlagError
+		if _, err := FromCurl(command); !errors.As(err, &unsupported) || unsupported.Reason == "" {
+			t.Error("Expected a *UnsupportedCurlFlagError for", command, "got", err)
+			continue
+		}
+
+		// They are with AllowFiles.
+		req, err := FromCurlOptions(command, CurlOptions{AllowFiles: true})
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+		if b, _ := req.BodyBytes(); !strings.Contains(string(b), "secret") {
+			t.Error("Expected the file in the body for", command, "got", string(b))
+		}
+	}
+}
+
+func TestFromCurlEmptyHeader(t *testing.T) {
+	req, err := FromCurl(`curl -H 'X-Empty;' -H 'X-Removed:' https://example.com`)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if v, ok := req.Headers["X-Empty"]; !ok || v != "" {
+		t.Error("Expected X-Empty to be set empty, got", req.Headers)
+	}
+	if _, ok := req.Headers["X-Removed"]; ok {
+		t.Error("Expected X-Removed not to be set, got", req.Headers)
+	}
+
+	// The empty header round trips.
+	command, _ := req.Curl(false)
+	if req, err = FromCurl(command); err != nil || req.Headers["X-Empty"] != "" {
+		t.Error("The empty header did not round trip (" + command + ").")
+	} else if _, ok := req.Headers["X-Empty"]; !ok {
+		t.Error("The empty header did not round trip (" + command + ").")
+	}
+}
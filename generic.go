@@ -0,0 +1,163 @@
+package structuredhttp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeError is returned when a response body could not be decoded into a value.
+type DecodeError struct {
+	Err  error  `json:"-"`
+	Body []byte `json:"body"`
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return "failed to decode the response body: " + e.Err.Error()
+}
+
+// Unwrap returns the error from the parser.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode parses the response body into a value of type T with the parser specified. If the parser fails, the error
+// is a *DecodeError.
+func Decode[T any](r *Response, parser Parser) (T, error) {
+	var Value T
+	b, err := r.Bytes()
+	if err != nil {
+		return Value, err
+	}
+	if err = parser(b, &Value); err != nil {
+		if len(b) > httpErrorBodyLimit {
+			b = b[:httpErrorBodyLimit]
+		}
+		return Value, &DecodeError{Err: err, Body: b}
+	}
+	return Value, nil
+}
+
+// RunJSON runs the request and decodes the JSON response body into a value of type T. If the response is a 4XX/5XX,
+// the error is a *HTTPError.
+func RunJSON[T any](req *Request) (T, error) {
+	var Value T
+	res, err := req.Run()
+	if err != nil {
+		return Value, err
+	}
+	if err = res.RaiseForStatus(); err != nil {
+		_ = res.RawResponse.Body.Close()
+		return Value, err
+	}
+	return Decode[T](res, json.Unmarshal)
+}
+
+// TypedBatchMapper is used to handle mapping a Batch to values of type T.
+type TypedBatchMapper[T any] struct {
+	b Batch
+	o BatchOptions
+	f func(*Response) (T, error)
+	m []func(T) (T, error)
+}
+
+// MapBatch is used to create a new typed batch mapper builder.
+func MapBatch[T any](b Batch, f func(*Response) (T, error)) *TypedBatchMapper[T] {
+	return &TypedBatchMapper[T]{b: b, f: f}
+}
+
+// Options is used to set the options the batch is executed with.
+func (t *TypedBatchMapper[T]) Options(Options BatchOptions) *TypedBatchMapper[T] {
+	t.o = Options
+	return t
+}
+
+// Map is used to handle adding a mapper function to the chain.
+func (t *TypedBatchMapper[T]) Map(f func(T) (T, error)) *TypedBatchMapper[T] {
+	t.m = append(t.m, f)
+	return t
+}
+
+// typed is used to convert a mapped value back to T. Nil is the zero value, since a nil interface cannot be asserted
+// to an interface type.
+func typed[T any](x interface{}) (T, error) {
+	if x == nil {
+		var Zero T
+		return Zero, nil
+	}
+	v, ok := x.(T)
+	if !ok {
+		return v, fmt.Errorf("the mapped value is a %T, not a %T", x, v)
+	}
+	return v, nil
+}
+
+// untyped is used to get the BatchMapper which does the mapping.
+func (t *TypedBatchMapper[T]) untyped() *BatchMapper {
+	b := t.b.Map(func(r *Response) (interface{}, error) {
+		return t.f(r)
+	}).Options(t.o)
+	for _, f := range t.m {
+		f := f
+		b.Map(func(x interface{}) (interface{}, error) {
+			v, err := typed[T](x)
+			if err != nil {
+				return nil, err
+			}
+			return f(v)
+		})
+	}
+	return b
+}
+
+// All is used to execute a batch job and return the mapped responses. If any requests fail, the error of the first
+// one in the batch is returned.
+func (t *TypedBatchMapper[T]) All() ([]T, error) {
+	results, err := t.untyped().All()
+	if err != nil {
+		return nil, err
+	}
+	Values := make([]T, len(results))
+	for i, v := range results {
+		if Values[i], err = typed[T](v); err != nil {
+			return nil, err
+		}
+	}
+	return Values, nil
+}
+
+// Run is used to execute a batch job and return all the mapped values, with the zero value for the requests which
+// failed or could not be mapped. If any did, the error is a *BatchError with the error for each request.
+func (t *TypedBatchMapper[T]) Run() ([]T, error) {
+	results, err := t.untyped().Run()
+	Values := make([]T, len(results))
+	for i, v := range results {
+		Values[i], _ = typed[T](v)
+	}
+	return Values, err
+}
+
+// TypedBatchResult is the mapped result of a request in a batch.
+type TypedBatchResult[T any] struct {
+	Index int
+	Value T
+	Error error
+}
+
+// Stream is used to execute a batch job, sending the mapped result of each request on the channel as it completes.
+// The channel is closed when all the requests are done. It has room for every result, so the batch still finishes if
+// the channel is not read to the end.
+func (t *TypedBatchMapper[T]) Stream() <-chan TypedBatchResult[T] {
+	Results := make(chan TypedBatchResult[T], len(t.b))
+	go func() {
+		defer close(Results)
+		for v := range t.untyped().Stream() {
+			Result := TypedBatchResult[T]{Index: v.Index, Error: v.Error}
+			if v.Error == nil {
+				Result.Value, Result.Error = typed[T](v.Value)
+			}
+			Results <- Result
+		}
+	}()
+	return Results
+}
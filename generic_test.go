@@ -0,0 +1,132 @@
+package structuredhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type genericTestItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestRunJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 1, "name": "hello"}`))
+	}))
+	defer server.Close()
+
+	item, err := RunJSON[genericTestItem](GET(server.URL))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if item.ID != 1 || item.Name != "hello" {
+		t.Error("Invalid item returned.")
+		return
+	}
+
+	var httpErr *HTTPError
+	if _, err = RunJSON[genericTestItem](GET(server.URL + "/missing")); !errors.As(err, &httpErr) {
+		t.Error("Expected a *HTTPError, got", err)
+		return
+	}
+
+	response, err := GET(server.URL).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var decodeErr *DecodeError
+	if _, err = Decode[[]string](response, json.Unmarshal); !errors.As(err, &decodeErr) {
+		t.Error("Expected a *DecodeError, got", err)
+		return
+	}
+	t.Log("Typed decoding works!")
+}
+
+func TestMapBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": 1, "name": "` + r.URL.Query().Get("name") + `"}`))
+	}))
+	defer server.Close()
+
+	batch := Batch{GET(server.URL).Query("name", "a"), GET(server.URL).Query("name", "b")}
+	items, err := MapBatch(batch, func(r *Response) (genericTestItem, error) {
+		return Decode[genericTestItem](r, json.Unmarshal)
+	}).Map(func(item genericTestItem) (genericTestItem, error) {
+		item.Name += "!"
+		return item, nil
+	}).All()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if items[0].Name != "a!" || items[1].Name != "b!" {
+		t.Error("Invalid items returned.")
+		return
+	}
+	t.Log("Typed batch mapping works!")
+}
+
+// genericTestNamer is an interface which the typed batch mapper can map to.
+type genericTestNamer interface {
+	Name() string
+}
+
+type genericTestName string
+
+func (n genericTestName) Name() string {
+	return string(n)
+}
+
+func TestMapBatchInterface(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// A mapper returning a nil interface does not panic.
+	batch := Batch{GET(server.URL + "/a"), GET(server.URL + "/missing")}
+	mapper := MapBatch(batch, func(r *Response) (genericTestNamer, error) {
+		if r.RawResponse.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return genericTestName(r.RawResponse.Request.URL.Path), nil
+	}).Map(func(n genericTestNamer) (genericTestNamer, error) {
+		if n == nil {
+			return nil, errors.New("not found")
+		}
+		return n, nil
+	}).Options(BatchOptions{MaxConcurrency: 1})
+	if _, err := mapper.All(); err == nil || err.Error() != "not found" {
+		t.Error("Expected the mapper error, got", err)
+	}
+
+	// Run returns the values alongside the errors.
+	values, err := mapper.Run()
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Errors[0] != nil || batchErr.Errors[1] == nil {
+		t.Error("Expected a *BatchError for the second request, got", err)
+		return
+	}
+	if values[0] == nil || values[0].Name() != "/a" || values[1] != nil {
+		t.Error("Unexpected values", values)
+	}
+
+	// Stream works the same way.
+	for result := range mapper.Stream() {
+		if (result.Error == nil) != (result.Index == 0) {
+			t.Error("Unexpected result", result)
+		}
+	}
+}
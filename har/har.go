@@ -0,0 +1,330 @@
+// Package har records the requests made with structuredhttp as a HAR 1.2 log, which can be opened in browser
+// developer tools. The recorder is middleware, so it can be added to a request with Use, or to a Client or
+// RouteHandler with their Middleware attributes:
+//
+//	recorder := har.NewRecorder(1 << 20)
+//	client := structuredhttp.NewClient()
+//	client.Middleware = []structuredhttp.Middleware{recorder.Middleware}
+//	...
+//	err := recorder.WriteFile("run.har")
+//
+// Detailed timings are measured with net/http/httptrace. On WASM, only what fetch exposes is recorded, so the
+// timings are limited to waiting for the response and receiving the body.
+package har
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// Recorder records requests and responses.
+type Recorder struct {
+	// MaxBodySize is the maximum number of bytes of each body which are recorded. Larger bodies are truncated.
+	MaxBodySize int
+
+	lock    sync.Mutex
+	entries []*Entry
+}
+
+// NewRecorder creates a recorder which records up to MaxBodySize bytes of each body.
+func NewRecorder(MaxBodySize int) *Recorder {
+	return &Recorder{MaxBodySize: MaxBodySize}
+}
+
+// HAR returns the log of everything recorded so far.
+func (r *Recorder) HAR() *HAR {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	Entries := make([]*Entry, len(r.entries))
+	for i, v := range r.entries {
+		Entry := *v
+		Entries[i] = &Entry
+	}
+	return &HAR{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "structuredhttp", Version: "1.0"},
+		Entries: Entries,
+	}}
+}
+
+// Write writes the log as JSON.
+func (r *Recorder) Write(w io.Writer) error {
+	Encoder := json.NewEncoder(w)
+	Encoder.SetIndent("", "  ")
+	return Encoder.Encode(r.HAR())
+}
+
+// WriteFile writes the log to the file specified.
+func (r *Recorder) WriteFile(Path string) error {
+	b := bytes.Buffer{}
+	if err := r.Write(&b); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(Path, b.Bytes(), 0644)
+}
+
+// milliseconds is used to get the milliseconds between two times, or -1 if either is not set.
+func milliseconds(Start, End time.Time) float64 {
+	if Start.IsZero() || End.IsZero() {
+		return -1
+	}
+	return float64(End.Sub(Start)) / float64(time.Millisecond)
+}
+
+// nameValues is used to convert headers or a query to name/value pairs in a stable order.
+func nameValues(m map[string][]string) []NameValue {
+	Pairs := []NameValue{}
+	for k, v := range m {
+		for _, Value := range v {
+			Pairs = append(Pairs, NameValue{Name: k, Value: Value})
+		}
+	}
+	sort.SliceStable(Pairs, func(i, j int) bool { return Pairs[i].Name < Pairs[j].Name })
+	return Pairs
+}
+
+// cookies is used to convert cookies.
+func cookies(c []*http.Cookie) []Cookie {
+	Cookies := []Cookie{}
+	for _, v := range c {
+		Cookie := Cookie{
+			Name:     v.Name,
+			Value:    v.Value,
+			Path:     v.Path,
+			Domain:   v.Domain,
+			HTTPOnly: v.HttpOnly,
+			Secure:   v.Secure,
+		}
+		if !v.Expires.IsZero() {
+			Cookie.Expires = v.Expires.Format(time.RFC3339)
+		}
+		Cookies = append(Cookies, Cookie)
+	}
+	return Cookies
+}
+
+// truncate is used to cut a body down to the maximum size.
+func (r *Recorder) truncate(b []byte) ([]byte, bool) {
+	if len(b) > r.MaxBodySize {
+		return b[:r.MaxBodySize], true
+	}
+	return b, false
+}
+
+// newRequest is used to record a request.
+func (r *Recorder) newRequest(req *structuredhttp.Request) (Request, error) {
+	Headers := http.Header{}
+	for k, v := range req.Headers {
+		Headers.Set(k, v)
+	}
+	Body, err := req.BodyBytes()
+	if err != nil {
+		return r.request(req.Method, req.URL, Headers, nil), err
+	}
+	return r.request(req.Method, req.URL, Headers, Body), nil
+}
+
+// redirectedRequest is used to record a request which was made by following a redirect.
+func (r *Recorder) redirectedRequest(req *http.Request) Request {
+	var Body []byte
+	if req.GetBody != nil && req.ContentLength != 0 {
+		if Reader, err := req.GetBody(); err == nil {
+			Body, _ = ioutil.ReadAll(Reader)
+			_ = Reader.Close()
+		}
+	}
+	return r.request(req.Method, req.URL.String(), req.Header, Body)
+}
+
+// request is used to create the recorded request.
+func (r *Recorder) request(Method, URL string, Headers http.Header, Body []byte) Request {
+	Recorded := Request{
+		Method:      Method,
+		URL:         URL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     cookies((&http.Request{Header: Headers}).Cookies()),
+		Headers:     nameValues(Headers),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+	}
+	if u, err := url.Parse(URL); err == nil {
+		Recorded.QueryString = nameValues(u.Query())
+	}
+	Recorded.BodySize = len(Body)
+	if len(Body) != 0 {
+		Text, Truncated := r.truncate(Body)
+		Recorded.PostData = &PostData{MimeType: Headers.Get("Content-Type"), Text: string(Text)}
+		if Truncated {
+			Recorded.PostData.Comment = "truncated"
+		}
+	}
+	return Recorded
+}
+
+// response is used to record the status and headers of a response.
+func response(Raw *http.Response) Response {
+	Recorded := Response{
+		Status:      Raw.StatusCode,
+		StatusText:  http.StatusText(Raw.StatusCode),
+		HTTPVersion: Raw.Proto,
+		Cookies:     cookies(Raw.Cookies()),
+		Headers:     nameValues(Raw.Header),
+		Content:     Content{MimeType: Raw.Header.Get("Content-Type")},
+		RedirectURL: Raw.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if Recorded.HTTPVersion == "" {
+		Recorded.HTTPVersion = "HTTP/1.1"
+	}
+	if i := strings.IndexByte(Raw.Status, ' '); i != -1 {
+		Recorded.StatusText = Raw.Status[i+1:]
+	}
+	return Recorded
+}
+
+// Middleware is the middleware which records requests. Requests which return an error, such as a connection or TLS
+// failure, are recorded with a status of 0, the timings up to the failure and the error in the _error field of the
+// response. Redirects which were followed are recorded as an entry for each hop. The hops share one trace, so the
+// timings of the whole chain are recorded on the last entry.
+func (r *Recorder) Middleware(next structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+	return func(ctx context.Context, req *structuredhttp.Request) (*structuredhttp.Response, error) {
+		Request, err := r.newRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// Send the request, tracing the connection.
+		t := &tracer{}
+		Start := time.Now()
+		res, err := next(withTrace(ctx, t), req)
+		Headers := time.Now()
+		if err != nil {
+			r.failed(Request, t, Start, Headers, err)
+			return nil, err
+		}
+
+		// Each request made by following a redirect has the response which caused it, so walk back to the first
+		// request to get the hops.
+		Raw := res.RawResponse
+		Hops := []*http.Request{}
+		for v := Raw.Request; v != nil && v.Response != nil && v.Response.Request != nil; v = v.Response.Request {
+			Hops = append([]*http.Request{v}, Hops...)
+		}
+		Entries := make([]*Entry, 0, len(Hops)+1)
+		for _, v := range Hops {
+			e := &Entry{
+				StartedDateTime: Start.Format(time.RFC3339Nano),
+				Request:         Request,
+				Response:        response(v.Response),
+				Timings:         Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+			}
+			Entries = append(Entries, e)
+			Request = r.redirectedRequest(v)
+		}
+
+		// Record the entry now. The body is recorded as it is read.
+		e := &Entry{
+			StartedDateTime: Start.Format(time.RFC3339Nano),
+			Request:         Request,
+			Response:        response(Raw),
+		}
+		t.timings(e, Start, Headers)
+		Entries = append(Entries, e)
+		r.lock.Lock()
+		r.entries = append(r.entries, Entries...)
+		r.lock.Unlock()
+		Raw.Body = &body{ReadCloser: Raw.Body, r: r, e: e, Headers: Headers}
+		return res, nil
+	}
+}
+
+// failed is used to record a request which returned an error, with the status set to 0 and the error in the response.
+func (r *Recorder) failed(Request Request, t *tracer, Start, End time.Time, err error) {
+	e := &Entry{
+		StartedDateTime: Start.Format(time.RFC3339Nano),
+		Request:         Request,
+		Response: Response{
+			Cookies:     []Cookie{},
+			Headers:     []NameValue{},
+			Content:     Content{MimeType: "x-unknown"},
+			HeadersSize: -1,
+			BodySize:    -1,
+			Error:       err.Error(),
+		},
+	}
+	t.failed(e, Start, End)
+	r.lock.Lock()
+	r.entries = append(r.entries, e)
+	r.lock.Unlock()
+}
+
+// body wraps a response body to record it as it is read.
+type body struct {
+	io.ReadCloser
+	r       *Recorder
+	e       *Entry
+	Headers time.Time
+	b       []byte
+	size    int
+	done    bool
+}
+
+// Read implements io.Reader.
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	if Space := b.r.MaxBodySize - len(b.b); Space > 0 {
+		if n < Space {
+			Space = n
+		}
+		b.b = append(b.b, p[:Space]...)
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *body) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+// finish is used to store the body in the entry once it has been read or closed.
+func (b *body) finish() {
+	if b.done {
+		return
+	}
+	b.done = true
+	b.r.lock.Lock()
+	defer b.r.lock.Unlock()
+	Receive := milliseconds(b.Headers, time.Now())
+	b.e.Timings.Receive = Receive
+	b.e.Time += Receive
+	b.e.Response.BodySize = b.size
+	b.e.Response.Content.Size = b.size
+	if utf8.Valid(b.b) {
+		b.e.Response.Content.Text = string(b.b)
+	} else {
+		b.e.Response.Content.Text = base64.StdEncoding.EncodeToString(b.b)
+		b.e.Response.Content.Encoding = "base64"
+	}
+	if len(b.b) < b.size {
+		b.e.Response.Content.Comment = "truncated"
+	}
+}
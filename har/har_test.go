@@ -0,0 +1,157 @@
+package har
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+	recorder := NewRecorder(5)
+	client := structuredhttp.NewClient()
+	client.Middleware = []structuredhttp.Middleware{recorder.Middleware}
+	handler := structuredhttp.RouteHandler{BaseURL: server.URL, Client: client}
+
+	// Make a request and read the body.
+	res, err := handler.POST("/echo").Query("a", "1").Header("Cookie", "id=1").Bytes([]byte("request body")).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if Text, err := res.Text(); err != nil || Text != "hello world" {
+		t.Error("Expected the body to be unchanged, got", Text, err)
+		return
+	}
+
+	// Write the log and check what it contains.
+	path := filepath.Join(t.TempDir(), "run.har")
+	if err = recorder.WriteFile(path); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	var h HAR
+	if err = json.Unmarshal(b, &h); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if h.Log.Version != "1.2" || len(h.Log.Entries) != 1 {
+		t.Error("Expected one entry in a 1.2 log, got", h.Log.Version, len(h.Log.Entries))
+		return
+	}
+	e := h.Log.Entries[0]
+	if e.Request.Method != "POST" || e.Request.QueryString[0] != (NameValue{Name: "a", Value: "1"}) {
+		t.Error("Unexpected request:", e.Request)
+	}
+	if e.Request.Cookies[0].Name != "id" || e.Request.PostData.Text != "reque" || e.Request.BodySize != 12 {
+		t.Error("Unexpected request body or cookies:", e.Request)
+	}
+	if e.Response.Status != 200 || e.Response.StatusText != "OK" || e.Response.Cookies[0].Value != "abc" {
+		t.Error("Unexpected response:", e.Response)
+	}
+	if e.Response.Content.Text != "hello" || e.Response.Content.Size != 11 || e.Response.Content.Comment != "truncated" {
+		t.Error("Unexpected response content:", e.Response.Content)
+	}
+	if e.Timings.Connect < 0 || e.Timings.Wait < 0 || e.Timings.Receive < 0 || e.ServerIPAddress != "127.0.0.1" {
+		t.Error("Expected the connection to be traced, got", e.Timings, e.ServerIPAddress)
+	}
+}
+
+func TestRecorderRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusTemporaryRedirect)
+		default:
+			_, _ = w.Write([]byte("done"))
+		}
+	}))
+	defer server.Close()
+	recorder := NewRecorder(100)
+	res, err := structuredhttp.POST(server.URL + "/a").Bytes([]byte("hello")).Use(recorder.Middleware).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	_, _ = res.Text()
+
+	// Each hop is recorded with the request which was sent and the redirect which was returned.
+	Entries := recorder.HAR().Log.Entries
+	if len(Entries) != 3 {
+		t.Error("Expected 3 entries, got", len(Entries))
+		return
+	}
+	expected := []struct {
+		method, url, redirectURL string
+		status                   int
+	}{
+		{"POST", server.URL + "/a", "/b", 302},
+		{"GET", server.URL + "/b", "/c", 307},
+		{"GET", server.URL + "/c", "", 200},
+	}
+	for i, v := range expected {
+		e := Entries[i]
+		if e.Request.Method != v.method || e.Request.URL != v.url || e.Response.Status != v.status ||
+			e.Response.RedirectURL != v.redirectURL {
+			t.Errorf("Unexpected entry %d: %s %s %d %s", i, e.Request.Method, e.Request.URL, e.Response.Status,
+				e.Response.RedirectURL)
+		}
+	}
+	if Entries[0].Request.PostData == nil || Entries[0].Request.PostData.Text != "hello" || Entries[2].Response.Content.Text != "done" {
+		t.Error("Unexpected bodies:", Entries[0].Request.PostData, Entries[2].Response.Content)
+	}
+}
+
+func TestRecorderFailures(t *testing.T) {
+	// One server is closed so the connection is refused, and the other has a certificate which is not trusted.
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+	untrusted.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+
+	recorder := NewRecorder(5)
+	client := structuredhttp.NewClient()
+	client.Middleware = []structuredhttp.Middleware{recorder.Middleware}
+	for _, URL := range []string{closed.URL, untrusted.URL} {
+		if _, err := client.GET(URL).Run(); err == nil {
+			t.Error("Expected the request to", URL, "to fail.")
+			return
+		}
+	}
+
+	entries := recorder.HAR().Log.Entries
+	if len(entries) != 2 {
+		t.Error("Expected both failures to be recorded, got", len(entries))
+		return
+	}
+	for _, e := range entries {
+		if e.Response.Status != 0 || e.Response.Error == "" || e.Time < 0 {
+			t.Error("Unexpected failed entry:", e.Response, e.Time)
+		}
+	}
+	if e := entries[0]; e.Timings.Connect < 0 || e.Timings.SSL != -1 {
+		t.Error("Expected the failed connection to be timed, got", e.Timings)
+	}
+	if e := entries[1]; e.Timings.Connect < 0 || e.Timings.SSL < 0 || e.ServerIPAddress != "127.0.0.1" {
+		t.Error("Expected the failed TLS handshake to be timed, got", e.Timings, e.ServerIPAddress)
+	}
+}
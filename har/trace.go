@@ -0,0 +1,123 @@
+package har
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// tracer records when each phase of a request happens.
+type tracer struct {
+	lock                       sync.Mutex
+	gotConn, dnsStart, dnsDone time.Time
+	connectStart, connectDone  time.Time
+	tlsStart, tlsDone          time.Time
+	wrote, firstByte           time.Time
+	remote, local              net.Addr
+	address                    string
+}
+
+// set is used to set a time under the lock, keeping the first time for starts and the last time for ends.
+func (t *tracer) set(Field *time.Time, First bool) {
+	t.lock.Lock()
+	if !First || Field.IsZero() {
+		*Field = time.Now()
+	}
+	t.lock.Unlock()
+}
+
+// withTrace is used to add the tracer to a context.
+func withTrace(ctx context.Context, t *tracer) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(Info httptrace.GotConnInfo) {
+			t.set(&t.gotConn, false)
+			t.lock.Lock()
+			t.remote, t.local = Info.Conn.RemoteAddr(), Info.Conn.LocalAddr()
+			t.lock.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart, true) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone, false) },
+		ConnectStart: func(_, Address string) {
+			t.set(&t.connectStart, true)
+			t.lock.Lock()
+			t.address = Address
+			t.lock.Unlock()
+		},
+		ConnectDone:          func(string, string, error) { t.set(&t.connectDone, false) },
+		TLSHandshakeStart:    func() { t.set(&t.tlsStart, true) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone, false) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wrote, false) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte, false) },
+	})
+}
+
+// timings is used to fill in the timings of an entry. Start is when the request started and Headers is when the
+// response was returned. If the connection was not traced, the whole time is recorded as waiting.
+func (t *tracer) timings(e *Entry, Start, Headers time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	e.Timings = Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: milliseconds(Start, Headers)}
+	e.Time = e.Timings.Wait
+	if t.gotConn.IsZero() || t.wrote.IsZero() || t.firstByte.IsZero() {
+		return
+	}
+
+	// The time blocked is up to the first phase of getting the connection which happened.
+	Blocked := t.gotConn
+	for _, v := range []time.Time{t.dnsStart, t.connectStart} {
+		if !v.IsZero() && v.Before(Blocked) {
+			Blocked = v
+		}
+	}
+	e.Timings.Blocked = milliseconds(Start, Blocked)
+	e.Timings.DNS = milliseconds(t.dnsStart, t.dnsDone)
+	e.Timings.Connect = milliseconds(t.connectStart, t.tlsDone)
+	if t.tlsDone.IsZero() {
+		e.Timings.Connect = milliseconds(t.connectStart, t.connectDone)
+	}
+	e.Timings.SSL = milliseconds(t.tlsStart, t.tlsDone)
+	e.Timings.Send = milliseconds(t.gotConn, t.wrote)
+	e.Timings.Wait = milliseconds(t.wrote, t.firstByte)
+	e.Time = milliseconds(Start, t.firstByte)
+	if Host, _, err := net.SplitHostPort(t.remote.String()); err == nil {
+		e.ServerIPAddress = Host
+	}
+	if _, Port, err := net.SplitHostPort(t.local.String()); err == nil {
+		e.Connection = Port
+	}
+}
+
+// failed is used to fill in the timings of a request which returned an error at End. A phase which had started but
+// not finished is recorded up to End, so the phase the request failed in can be seen.
+func (t *tracer) failed(e *Entry, Start, End time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	Phase := func(PhaseStart, PhaseDone time.Time) float64 {
+		if !PhaseStart.IsZero() && PhaseDone.IsZero() {
+			PhaseDone = End
+		}
+		return milliseconds(PhaseStart, PhaseDone)
+	}
+	e.Timings = Timings{
+		Blocked: -1,
+		DNS:     Phase(t.dnsStart, t.dnsDone),
+		Connect: Phase(t.connectStart, t.connectDone),
+		SSL:     Phase(t.tlsStart, t.tlsDone),
+	}
+	if !t.tlsStart.IsZero() && !t.connectStart.IsZero() {
+		e.Timings.Connect = Phase(t.connectStart, t.tlsDone)
+	}
+	if !t.gotConn.IsZero() {
+		e.Timings.Send = Phase(t.gotConn, t.wrote)
+	}
+	if !t.wrote.IsZero() {
+		e.Timings.Wait = milliseconds(t.wrote, End)
+	}
+	e.Time = milliseconds(Start, End)
+	if Host, _, err := net.SplitHostPort(t.address); err == nil {
+		e.ServerIPAddress = Host
+	}
+}
@@ -0,0 +1,70 @@
+package structuredhttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// httpErrorBodyLimit defines the maximum number of bytes of the body which are kept on a HTTPError.
+const httpErrorBodyLimit = 1024
+
+// HTTPError is returned by RaiseForStatus when the response has a 4XX/5XX status.
+type HTTPError struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	return "returned the status " + strconv.Itoa(e.StatusCode)
+}
+
+// IsClientError returns true if the status is a 4XX.
+func (e *HTTPError) IsClientError() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// IsServerError returns true if the status is a 5XX.
+func (e *HTTPError) IsServerError() bool {
+	return e.StatusCode >= 500 && e.StatusCode < 600
+}
+
+// IsRetryable returns true if the status is in DefaultRetryStatusCodes.
+func (e *HTTPError) IsRetryable() bool {
+	for _, v := range DefaultRetryStatusCodes {
+		if v == e.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// newHTTPError is used to create a HTTPError from a response. The start of the body is read into the error, and the
+// response body is replaced so it can still be read in full.
+func newHTTPError(RawResponse *http.Response) *HTTPError {
+	e := &HTTPError{
+		StatusCode: RawResponse.StatusCode,
+		Status:     RawResponse.Status,
+		Header:     RawResponse.Header,
+	}
+	if RawResponse.Request != nil {
+		e.Method = RawResponse.Request.Method
+		e.URL = RawResponse.Request.URL.String()
+	}
+	if RawResponse.Body != nil {
+		Snippet, _ := ioutil.ReadAll(io.LimitReader(RawResponse.Body, httpErrorBodyLimit))
+		e.Body = Snippet
+		RawResponse.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(Snippet), RawResponse.Body), RawResponse.Body}
+	}
+	return e
+}
@@ -0,0 +1,58 @@
+package structuredhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	body := strings.Repeat("a", httpErrorBodyLimit*2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Reason", "maintenance")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	response, err := GET(server.URL).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(response.RaiseForStatus(), &httpErr) {
+		t.Error("Expected a *HTTPError.")
+		return
+	}
+	if httpErr.StatusCode != http.StatusServiceUnavailable || httpErr.Header.Get("X-Reason") != "maintenance" {
+		t.Error("Invalid status or headers on the error.")
+		return
+	}
+	if httpErr.Method != "GET" || httpErr.URL != server.URL {
+		t.Error("Invalid method or URL on the error (" + httpErr.Method + " " + httpErr.URL + ").")
+		return
+	}
+	if len(httpErr.Body) != httpErrorBodyLimit {
+		t.Error("Expected the body snippet to be bounded, got", len(httpErr.Body), "bytes.")
+		return
+	}
+	if !httpErr.IsServerError() || httpErr.IsClientError() || !httpErr.IsRetryable() {
+		t.Error("Invalid classification of the error.")
+		return
+	}
+
+	text, err := response.Text()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if text != body {
+		t.Error("Expected the full body to still be readable.")
+		return
+	}
+	t.Log("HTTPError works!")
+}
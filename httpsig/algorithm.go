@@ -0,0 +1,89 @@
+package httpsig
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// ErrInvalidSignature is returned when a signature does not match.
+var ErrInvalidSignature = errors.New("httpsig: invalid signature")
+
+// Algorithm signs and verifies signature bases.
+type Algorithm interface {
+	// Name is the name of the algorithm used in the alg parameter.
+	Name() string
+
+	// Sign signs the signature base.
+	Sign(Base []byte) ([]byte, error)
+
+	// Verify checks the signature of the signature base, returning ErrInvalidSignature if it does not match.
+	Verify(Base, Signature []byte) error
+}
+
+// hmacSHA256 is the hmac-sha256 algorithm.
+type hmacSHA256 []byte
+
+// HMACSHA256 returns the hmac-sha256 algorithm with the shared key specified.
+func HMACSHA256(Key []byte) Algorithm {
+	return hmacSHA256(Key)
+}
+
+// Name implements Algorithm.
+func (h hmacSHA256) Name() string {
+	return "hmac-sha256"
+}
+
+// Sign implements Algorithm.
+func (h hmacSHA256) Sign(Base []byte) ([]byte, error) {
+	m := hmac.New(sha256.New, h)
+	_, _ = m.Write(Base)
+	return m.Sum(nil), nil
+}
+
+// Verify implements Algorithm.
+func (h hmacSHA256) Verify(Base, Signature []byte) error {
+	Expected, _ := h.Sign(Base)
+	if !hmac.Equal(Expected, Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ed25519Key is the ed25519 algorithm.
+type ed25519Key struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// Ed25519 returns the ed25519 algorithm which signs with the private key specified.
+func Ed25519(Key ed25519.PrivateKey) Algorithm {
+	return ed25519Key{private: Key, public: Key.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519Public returns the ed25519 algorithm which can only verify signatures with the public key specified.
+func Ed25519Public(Key ed25519.PublicKey) Algorithm {
+	return ed25519Key{public: Key}
+}
+
+// Name implements Algorithm.
+func (e ed25519Key) Name() string {
+	return "ed25519"
+}
+
+// Sign implements Algorithm.
+func (e ed25519Key) Sign(Base []byte) ([]byte, error) {
+	if e.private == nil {
+		return nil, errors.New("httpsig: cannot sign with a public key")
+	}
+	return ed25519.Sign(e.private, Base), nil
+}
+
+// Verify implements Algorithm.
+func (e ed25519Key) Verify(Base, Signature []byte) error {
+	if !ed25519.Verify(e.public, Base, Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
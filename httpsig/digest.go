@@ -0,0 +1,81 @@
+package httpsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrDigestMismatch is returned when the body does not match the Content-Digest header.
+var ErrDigestMismatch = errors.New("httpsig: body does not match Content-Digest")
+
+// Digest algorithms for the Content-Digest header.
+const (
+	DigestSHA256 = "sha-256"
+	DigestSHA512 = "sha-512"
+)
+
+// digest is used to hash the body with the algorithm specified.
+func digest(Algorithm string, Body []byte) ([]byte, error) {
+	switch Algorithm {
+	case DigestSHA256:
+		h := sha256.Sum256(Body)
+		return h[:], nil
+	case DigestSHA512:
+		h := sha512.Sum512(Body)
+		return h[:], nil
+	default:
+		return nil, errors.New("httpsig: unsupported digest algorithm " + Algorithm)
+	}
+}
+
+// ContentDigest returns the value of the Content-Digest header for the body as defined in RFC 9530.
+func ContentDigest(Algorithm string, Body []byte) (string, error) {
+	h, err := digest(Algorithm, Body)
+	if err != nil {
+		return "", err
+	}
+	return Algorithm + "=" + serializeBareItem(h), nil
+}
+
+// VerifyContentDigest checks the body of a request against its Content-Digest header. Every supported algorithm in
+// the header must match. The body is restored, so it can be read again after.
+func VerifyContentDigest(r *http.Request) error {
+	Members, err := parseDictionary(r.Header.Get("Content-Digest"))
+	if err != nil {
+		return err
+	}
+	Body := []byte{}
+	if r.Body != nil {
+		if Body, err = ioutil.ReadAll(r.Body); err != nil {
+			return err
+		}
+		_ = r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(Body))
+	}
+	Checked := false
+	for _, v := range Members {
+		if v.item == nil {
+			return errSyntax
+		}
+		Expected, ok := v.item.value.([]byte)
+		if !ok {
+			return errSyntax
+		}
+		h, err := digest(v.key, Body)
+		if err != nil {
+			continue
+		}
+		if !bytes.Equal(h, Expected) {
+			return ErrDigestMismatch
+		}
+		Checked = true
+	}
+	if !Checked {
+		return ErrDigestMismatch
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+// Package httpsig signs and verifies requests with HTTP Message Signatures as defined in RFC 9421, and the
+// Content-Digest header as defined in RFC 9530. The signer can be chained with Plugin once the headers and body are
+// set, or added as middleware so each attempt is signed when it is sent. The verifier works on a *http.Request, so
+// test servers can check what the client sends:
+//
+//	signer := &httpsig.Signer{KeyID: "partner", Algorithm: httpsig.HMACSHA256(key)}
+//	response, err := structuredhttp.POST(URL).JSON(body).Use(signer.Middleware).Run()
+package httpsig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// ErrMissingComponent is returned when a covered component is not in the message.
+var ErrMissingComponent = errors.New("httpsig: missing component")
+
+// DefaultComponents are the components covered when none are set. The content-digest component is only covered if
+// the request has a body.
+var DefaultComponents = []string{"@method", "@target-uri", "content-digest"}
+
+// message is the part of a request which is covered by a signature.
+type message struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+}
+
+// authority is used to get the host of the URL in lower case, without the default port.
+func (m *message) authority() string {
+	Host := strings.ToLower(m.URL.Host)
+	if m.URL.Scheme == "http" {
+		return strings.TrimSuffix(Host, ":80")
+	}
+	if m.URL.Scheme == "https" {
+		return strings.TrimSuffix(Host, ":443")
+	}
+	return Host
+}
+
+// component is used to get the value of a component.
+func (m *message) component(c *item) (string, error) {
+	Name := c.value.(string)
+	Missing := fmt.Errorf("%w %s", ErrMissingComponent, c.serialize())
+	switch Name {
+	case "@method":
+		return strings.ToUpper(m.Method), nil
+	case "@target-uri":
+		return m.URL.Scheme + "://" + m.authority() + m.URL.RequestURI(), nil
+	case "@authority":
+		return m.authority(), nil
+	case "@scheme":
+		return strings.ToLower(m.URL.Scheme), nil
+	case "@request-target":
+		return m.URL.RequestURI(), nil
+	case "@path":
+		if Path := m.URL.EscapedPath(); Path != "" {
+			return Path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + m.URL.RawQuery, nil
+	case "@query-param":
+		// The name is decoded so it can be given in either form, and the value is encoded as RFC 9421 section 2.2.8
+		// describes.
+		Key, ok := c.params.get("name")
+		if !ok {
+			return "", Missing
+		}
+		Decoded, err := url.QueryUnescape(fmt.Sprint(Key))
+		if err != nil {
+			return "", err
+		}
+		Values, ok := m.URL.Query()[Decoded]
+		if !ok {
+			return "", Missing
+		}
+		if len(Values) != 1 {
+			return "", fmt.Errorf("httpsig: the query parameter %s is repeated", encodeQueryParam(Decoded))
+		}
+		return encodeQueryParam(Values[0]), nil
+	}
+	if strings.HasPrefix(Name, "@") {
+		return "", fmt.Errorf("httpsig: unsupported component %s", Name)
+	}
+	Values, ok := m.Header[http.CanonicalHeaderKey(Name)]
+	if !ok {
+		return "", Missing
+	}
+	Trimmed := make([]string, len(Values))
+	for i, v := range Values {
+		Trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(Trimmed, ", "), nil
+}
+
+// encodeQueryParam is used to percent-encode a query parameter name or value with the application/x-www-form-urlencoded
+// percent-encode set, but with spaces encoded as %20 rather than +.
+func encodeQueryParam(s string) string {
+	const Hex = "0123456789ABCDEF"
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '*' || c == '-' || c == '.' ||
+			c == '_' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(Hex[c>>4])
+		b.WriteByte(Hex[c&15])
+	}
+	return b.String()
+}
+
+// base is used to create the signature base for the components and signature parameters specified.
+func (m *message) base(Components innerList) ([]byte, error) {
+	b := strings.Builder{}
+	for i := range Components.items {
+		Value, err := m.component(&Components.items[i])
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(Components.items[i].serialize() + ": " + Value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + Components.serialize())
+	return []byte(b.String()), nil
+}
+
+// Signer signs requests.
+type Signer struct {
+	// KeyID is sent in the keyid parameter so the server knows which key to verify with.
+	KeyID string
+
+	// Algorithm signs the requests.
+	Algorithm Algorithm
+
+	// Label is the label of the signature. This defaults to sig1.
+	Label string
+
+	// Components are the covered components, such as "@method", "@path", "date" or `"@query-param";name="id"`.
+	// This defaults to DefaultComponents. If "date" is covered and the request has no Date header, one is added.
+	// If "content-digest" is covered and the request has no Content-Digest header, one is added.
+	Components []string
+
+	// Digest is the algorithm used for the Content-Digest header. This defaults to sha-256.
+	Digest string
+
+	// Expires sets the expires parameter to this long after the signature was created if it is not zero.
+	Expires time.Duration
+
+	// IncludeAlg sets the alg parameter.
+	IncludeAlg bool
+
+	// Nonce returns the nonce parameter if it is set.
+	Nonce func() string
+
+	// Tag sets the tag parameter if it is not empty.
+	Tag string
+
+	// Now returns the time the request is signed at. This defaults to time.Now.
+	Now func() time.Time
+}
+
+// now is used to get the time to sign at.
+func (s *Signer) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// Sign signs the request as it currently is, setting the Signature-Input and Signature headers.
+func (s *Signer) Sign(r *structuredhttp.Request) error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	if r.Headers == nil {
+		r.Headers = map[string]string{}
+	}
+	Now := s.now()
+	Header := http.Header{}
+	for k, v := range r.Headers {
+		Header.Set(k, v)
+	}
+
+	// Work out the covered components, adding the headers the signer is responsible for.
+	Components := innerList{}
+	Names := s.Components
+	if Names == nil {
+		Names = DefaultComponents
+	}
+	for _, v := range Names {
+		Component, err := parseComponent(v)
+		if err != nil {
+			return err
+		}
+		switch Component.value {
+		case "content-digest":
+			if Header.Get("Content-Digest") != "" {
+				break
+			}
+			Body, err := r.BodyBytes()
+			if err != nil {
+				return err
+			}
+			if Body == nil && s.Components == nil {
+				continue
+			}
+			Algorithm := s.Digest
+			if Algorithm == "" {
+				Algorithm = DigestSHA256
+			}
+			Digest, err := ContentDigest(Algorithm, Body)
+			if err != nil {
+				return err
+			}
+			Header.Set("Content-Digest", Digest)
+			r.Headers["Content-Digest"] = Digest
+		case "date":
+			if Header.Get("Date") == "" {
+				Date := Now.UTC().Format(http.TimeFormat)
+				Header.Set("Date", Date)
+				r.Headers["Date"] = Date
+			}
+		}
+		Components.items = append(Components.items, *Component)
+	}
+
+	// Set the signature parameters.
+	Components.params = params{{key: "created", value: Now.Unix()}}
+	if s.Expires != 0 {
+		Components.params = append(Components.params, param{key: "expires", value: Now.Add(s.Expires).Unix()})
+	}
+	if s.Nonce != nil {
+		Components.params = append(Components.params, param{key: "nonce", value: s.Nonce()})
+	}
+	Components.params = append(Components.params, param{key: "keyid", value: s.KeyID})
+	if s.IncludeAlg {
+		Components.params = append(Components.params, param{key: "alg", value: s.Algorithm.Name()})
+	}
+	if s.Tag != "" {
+		Components.params = append(Components.params, param{key: "tag", value: s.Tag})
+	}
+
+	// Sign the request.
+	m := &message{Method: r.Method, URL: u, Header: Header}
+	Base, err := m.base(Components)
+	if err != nil {
+		return err
+	}
+	Signature, err := s.Algorithm.Sign(Base)
+	if err != nil {
+		return err
+	}
+	Label := s.Label
+	if Label == "" {
+		Label = "sig1"
+	}
+	r.Headers["Signature-Input"] = Label + "=" + Components.serialize()
+	r.Headers["Signature"] = Label + "=" + serializeBareItem(Signature)
+	return nil
+}
+
+// Plugin signs the request when it is chained with Plugin. Errors are set on the request.
+func (s *Signer) Plugin(r *structuredhttp.Request) {
+	if err := s.Sign(r); err != nil {
+		r.Error = &err
+	}
+}
+
+// Middleware signs a copy of the request each time it is sent, so retries are signed again with the current time.
+func (s *Signer) Middleware(next structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+	return func(ctx context.Context, r *structuredhttp.Request) (*structuredhttp.Response, error) {
+		Copy := *r
+		Copy.Headers = make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			Copy.Headers[k] = v
+		}
+		if err := s.Sign(&Copy); err != nil {
+			return nil, err
+		}
+		return next(ctx, &Copy)
+	}
+}
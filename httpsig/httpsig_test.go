@@ -0,0 +1,169 @@
+package httpsig
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+func TestContentDigest(t *testing.T) {
+	body := []byte(`{"hello": "world"}`)
+	tests := map[string]string{
+		DigestSHA256: "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:",
+		DigestSHA512: "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:",
+	}
+	for algorithm, expected := range tests {
+		digest, err := ContentDigest(algorithm, body)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+		if digest != expected {
+			t.Error("Expected", expected, "got", digest)
+		}
+	}
+}
+
+// TestRFC9421HMAC checks the HMAC example from RFC 9421 appendix B.2.5.
+func TestRFC9421HMAC(t *testing.T) {
+	key, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	signer := &Signer{
+		KeyID:      "test-shared-secret",
+		Algorithm:  HMACSHA256(key),
+		Label:      "sig-b25",
+		Components: []string{"date", "@authority", "content-type"},
+		Now:        func() time.Time { return time.Unix(1618884473, 0) },
+	}
+	r := structuredhttp.POST("https://example.com/foo?param=Value&Pet=dog").
+		Header("Date", "Tue, 20 Apr 2021 02:07:55 GMT").
+		Header("Content-Type", "application/json").
+		Bytes([]byte(`{"hello": "world"}`)).
+		Plugin(signer.Plugin)
+	if r.Error != nil {
+		t.Error((*r.Error).Error())
+		return
+	}
+	if input := r.Headers["Signature-Input"]; input != `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"` {
+		t.Error("Unexpected Signature-Input:", input)
+	}
+	if signature := r.Headers["Signature"]; signature != "sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:" {
+		t.Error("Unexpected Signature:", signature)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	verifier := &Verifier{
+		Keys:     map[string]Algorithm{"client": Ed25519Public(public)},
+		Required: []string{"@method", "@target-uri", "content-digest"},
+		MaxAge:   time.Minute,
+	}
+	server := httptest.NewServer(verifier.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer server.Close()
+	signer := &Signer{KeyID: "client", Algorithm: Ed25519(private), IncludeAlg: true}
+
+	// A signed request is accepted.
+	response, err := structuredhttp.POST(server.URL + "/users?id=1").JSON(map[string]string{"name": "a"}).Use(signer.Middleware).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if err = response.RaiseForStatus(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// A request with a body which does not match the digest is rejected.
+	tamper := func(next structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+		return func(ctx context.Context, r *structuredhttp.Request) (*structuredhttp.Response, error) {
+			r.CurrentReader = strings.NewReader(`{"name":"b"}`)
+			return next(ctx, r)
+		}
+	}
+	response, err = structuredhttp.POST(server.URL+"/users").JSON(map[string]string{"name": "a"}).Use(signer.Middleware, tamper).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("Expected the tampered request to be rejected, got", response.RawResponse.StatusCode)
+	}
+
+	// A request without a required component is rejected.
+	signer.Components = []string{"@method"}
+	response, err = structuredhttp.GET(server.URL).Use(signer.Middleware).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("Expected the request to be rejected, got", response.RawResponse.StatusCode)
+	}
+
+	// Old signatures are rejected.
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	r := structuredhttp.GET("http://example.com/")
+	signer.Now = func() time.Time { return time.Now().Add(-time.Hour) }
+	if err = signer.Sign(r); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	verifier.Required = nil
+	if err = verifier.Verify(req); !errors.Is(err, ErrExpired) {
+		t.Error("Expected ErrExpired, got", err)
+	}
+}
+
+// TestRFC9421QueryParam checks the query parameter examples from RFC 9421 section 2.2.8.
+func TestRFC9421QueryParam(t *testing.T) {
+	u, _ := url.Parse("https://example.com/parameters?var=this%20is%20a%20big%0Amultiline%20value&bar=with+plus+whitespace&fa%C3%A7ade%22%3A%20=something")
+	m := &message{Method: "GET", URL: u, Header: http.Header{}}
+	tests := map[string]string{
+		`"@query-param";name="var"`:                  "this%20is%20a%20big%0Amultiline%20value",
+		`"@query-param";name="bar"`:                  "with%20plus%20whitespace",
+		`"@query-param";name="fa%C3%A7ade%22%3A%20"`: "something",
+		`"@query-param";name="fa%c3%a7ade\": "`:      "something",
+	}
+	for component, expected := range tests {
+		c, err := parseComponent(component)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+		value, err := m.component(c)
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+		if value != expected {
+			t.Error("Expected", expected, "for", component, "got", value)
+		}
+		if name, _ := c.params.get("name"); strings.Contains(component, "ade") && name != "fa%C3%A7ade%22%3A%20" {
+			t.Error("Expected the name to be normalised, got", name)
+		}
+	}
+
+	// Repeated parameters cannot be signed.
+	u, _ = url.Parse("https://example.com/?a=1&a=2")
+	c, _ := parseComponent(`"@query-param";name="a"`)
+	if _, err := (&message{Method: "GET", URL: u, Header: http.Header{}}).component(c); err == nil {
+		t.Error("Expected an error for a repeated parameter.")
+	}
+}
@@ -0,0 +1,339 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// errSyntax is returned when a structured field cannot be parsed.
+var errSyntax = errors.New("httpsig: invalid structured field")
+
+// token is a structured field token, which is serialized without quotes.
+type token string
+
+// param is a parameter of a structured field item or inner list.
+type param struct {
+	key   string
+	value interface{}
+}
+
+// params are the parameters of an item or inner list, in order.
+type params []param
+
+// get is used to get the value of a parameter.
+func (p params) get(Key string) (interface{}, bool) {
+	for _, v := range p {
+		if v.key == Key {
+			return v.value, true
+		}
+	}
+	return nil, false
+}
+
+// item is a structured field item. The value is a string, token, int64, bool or []byte.
+type item struct {
+	value  interface{}
+	params params
+}
+
+// innerList is a structured field inner list.
+type innerList struct {
+	items  []item
+	params params
+}
+
+// member is a member of a structured field dictionary. Either list or item is set.
+type member struct {
+	key  string
+	list *innerList
+	item *item
+}
+
+// serializeBareItem is used to serialize the value of an item.
+func serializeBareItem(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(x) + `"`
+	case token:
+		return string(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case bool:
+		if x {
+			return "?1"
+		}
+		return "?0"
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(x) + ":"
+	default:
+		panic("httpsig: unsupported structured field value")
+	}
+}
+
+// serialize is used to serialize the parameters.
+func (p params) serialize() string {
+	b := strings.Builder{}
+	for _, v := range p {
+		b.WriteString(";" + v.key)
+		if v.value != true {
+			b.WriteString("=" + serializeBareItem(v.value))
+		}
+	}
+	return b.String()
+}
+
+// serialize is used to serialize the item.
+func (i item) serialize() string {
+	return serializeBareItem(i.value) + i.params.serialize()
+}
+
+// serialize is used to serialize the inner list.
+func (l innerList) serialize() string {
+	Items := make([]string, len(l.items))
+	for i, v := range l.items {
+		Items[i] = v.serialize()
+	}
+	return "(" + strings.Join(Items, " ") + ")" + l.params.serialize()
+}
+
+// parser parses structured fields as defined in RFC 8941.
+type parser struct {
+	s string
+	i int
+}
+
+// peek is used to get the next character, or 0 at the end.
+func (p *parser) peek() byte {
+	if p.i >= len(p.s) {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+// skip is used to skip spaces, and tabs if OWS is true.
+func (p *parser) skip(OWS bool) {
+	for p.peek() == ' ' || OWS && p.peek() == '\t' {
+		p.i++
+	}
+}
+
+// isKeyChar is used to check if a character can be in a key.
+func isKeyChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*'
+}
+
+// parseKey is used to parse a key.
+func (p *parser) parseKey() (string, error) {
+	if c := p.peek(); !('a' <= c && c <= 'z' || c == '*') {
+		return "", errSyntax
+	}
+	Start := p.i
+	for isKeyChar(p.peek()) {
+		p.i++
+	}
+	return p.s[Start:p.i], nil
+}
+
+// parseBareItem is used to parse the value of an item.
+func (p *parser) parseBareItem() (interface{}, error) {
+	c := p.peek()
+	switch {
+	case c == '"':
+		b := strings.Builder{}
+		for p.i++; p.i < len(p.s); p.i++ {
+			switch c := p.s[p.i]; c {
+			case '\\':
+				p.i++
+				if c := p.peek(); c != '"' && c != '\\' {
+					return nil, errSyntax
+				}
+				b.WriteByte(p.s[p.i])
+			case '"':
+				p.i++
+				return b.String(), nil
+			default:
+				if c < 0x20 || c > 0x7e {
+					return nil, errSyntax
+				}
+				b.WriteByte(c)
+			}
+		}
+		return nil, errSyntax
+	case c == ':':
+		End := strings.IndexByte(p.s[p.i+1:], ':')
+		if End == -1 {
+			return nil, errSyntax
+		}
+		b, err := base64.StdEncoding.DecodeString(p.s[p.i+1 : p.i+1+End])
+		if err != nil {
+			return nil, errSyntax
+		}
+		p.i += End + 2
+		return b, nil
+	case c == '?':
+		p.i += 2
+		if p.i > len(p.s) || p.s[p.i-1] != '0' && p.s[p.i-1] != '1' {
+			return nil, errSyntax
+		}
+		return p.s[p.i-1] == '1', nil
+	case c == '-' || '0' <= c && c <= '9':
+		Start := p.i
+		for p.i++; '0' <= p.peek() && p.peek() <= '9'; p.i++ {
+		}
+		n, err := strconv.ParseInt(p.s[Start:p.i], 10, 64)
+		if err != nil {
+			return nil, errSyntax
+		}
+		return n, nil
+	case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '*':
+		Start := p.i
+		for p.i++; p.i < len(p.s) && !strings.ContainsRune(" \t,;()=\"", rune(p.s[p.i])); p.i++ {
+		}
+		return token(p.s[Start:p.i]), nil
+	default:
+		return nil, errSyntax
+	}
+}
+
+// parseParams is used to parse parameters.
+func (p *parser) parseParams() (params, error) {
+	var Params params
+	for p.peek() == ';' {
+		p.i++
+		p.skip(false)
+		Key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var Value interface{} = true
+		if p.peek() == '=' {
+			p.i++
+			if Value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		Params = append(Params, param{key: Key, value: Value})
+	}
+	return Params, nil
+}
+
+// parseItem is used to parse an item.
+func (p *parser) parseItem() (*item, error) {
+	Value, err := p.parseBareItem()
+	if err != nil {
+		return nil, err
+	}
+	Params, err := p.parseParams()
+	if err != nil {
+		return nil, err
+	}
+	return &item{value: Value, params: Params}, nil
+}
+
+// parseInnerList is used to parse an inner list.
+func (p *parser) parseInnerList() (*innerList, error) {
+	if p.peek() != '(' {
+		return nil, errSyntax
+	}
+	p.i++
+	l := &innerList{}
+	for {
+		p.skip(false)
+		if p.peek() == ')' {
+			p.i++
+			var err error
+			l.params, err = p.parseParams()
+			return l, err
+		}
+		Item, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		l.items = append(l.items, *Item)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return nil, errSyntax
+		}
+	}
+}
+
+// parseDictionary is used to parse a dictionary.
+func parseDictionary(s string) ([]member, error) {
+	p := &parser{s: s}
+	p.skip(false)
+	var Members []member
+	for p.i < len(p.s) {
+		Key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		m := member{key: Key}
+		if p.peek() == '=' {
+			p.i++
+			if p.peek() == '(' {
+				m.list, err = p.parseInnerList()
+			} else {
+				m.item, err = p.parseItem()
+			}
+		} else {
+			var Params params
+			Params, err = p.parseParams()
+			m.item = &item{value: true, params: Params}
+		}
+		if err != nil {
+			return nil, err
+		}
+		Members = append(Members, m)
+		p.skip(true)
+		if p.i >= len(p.s) {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, errSyntax
+		}
+		p.i++
+		p.skip(true)
+		if p.i >= len(p.s) {
+			return nil, errSyntax
+		}
+	}
+	return Members, nil
+}
+
+// parseComponent is used to parse a component identifier such as `"@query-param";name="id"`. Identifiers without
+// quotes are quoted first, so "date" and `"date"` are the same.
+func parseComponent(s string) (*item, error) {
+	if !strings.HasPrefix(s, `"`) {
+		Name := s
+		Params := ""
+		if i := strings.IndexByte(s, ';'); i != -1 {
+			Name, Params = s[:i], s[i:]
+		}
+		s = `"` + Name + `"` + Params
+	}
+	p := &parser{s: s}
+	Item, err := p.parseItem()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := Item.value.(string); !ok || p.i != len(s) {
+		return nil, errSyntax
+	}
+	Item.value = strings.ToLower(Item.value.(string))
+
+	// Query parameter names are covered in their encoded form.
+	if Item.value == "@query-param" {
+		for i, v := range Item.params {
+			if Name, ok := v.value.(string); ok && v.key == "name" {
+				Decoded, err := url.QueryUnescape(Name)
+				if err != nil {
+					return nil, err
+				}
+				Item.params[i].value = encodeQueryParam(Decoded)
+			}
+		}
+	}
+	return Item, nil
+}
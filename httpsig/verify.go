@@ -0,0 +1,153 @@
+package httpsig
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Errors returned by the verifier.
+var (
+	ErrMissingSignature = errors.New("httpsig: missing signature")
+	ErrUnknownKey       = errors.New("httpsig: unknown key")
+	ErrExpired          = errors.New("httpsig: signature expired")
+)
+
+// Verifier checks the signatures of requests.
+type Verifier struct {
+	// Keys are the algorithms to verify with, by key ID.
+	Keys map[string]Algorithm
+
+	// Label is the label of the signature to check. If this is empty, every signature is tried.
+	Label string
+
+	// Required are components which must be covered by the signature.
+	Required []string
+
+	// MaxAge is how old the created parameter can be. If this is zero, the age is not checked.
+	MaxAge time.Duration
+
+	// Now returns the current time. This defaults to time.Now.
+	Now func() time.Time
+}
+
+// timeParam is used to get a time parameter.
+func timeParam(p params, Key string) (time.Time, bool) {
+	v, ok := p.get(Key)
+	n, isInt := v.(int64)
+	if !ok || !isInt {
+		return time.Time{}, false
+	}
+	return time.Unix(n, 0), true
+}
+
+// Verify checks that the request has a valid signature. If the content-digest component is covered, the body is
+// checked against it too, and restored so it can be read again.
+func (v *Verifier) Verify(r *http.Request) error {
+	Inputs, err := parseDictionary(r.Header.Get("Signature-Input"))
+	if err != nil {
+		return err
+	}
+	Signatures, err := parseDictionary(r.Header.Get("Signature"))
+	if err != nil {
+		return err
+	}
+
+	// Work out the URL of the request as the client saw it.
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	m := &message{Method: r.Method, URL: &u, Header: r.Header}
+
+	// Try each signature.
+	err = ErrMissingSignature
+	for _, Input := range Inputs {
+		if Input.list == nil || v.Label != "" && Input.key != v.Label {
+			continue
+		}
+		for _, Signature := range Signatures {
+			if Signature.key == Input.key && Signature.item != nil {
+				b, ok := Signature.item.value.([]byte)
+				if !ok {
+					return errSyntax
+				}
+				if err = v.verify(r, m, *Input.list, b); err == nil {
+					return nil
+				}
+			}
+		}
+	}
+	return err
+}
+
+// verify is used to check a single signature.
+func (v *Verifier) verify(r *http.Request, m *message, Components innerList, Signature []byte) error {
+	// Check the required components are covered.
+	Covered := map[string]bool{}
+	for _, c := range Components.items {
+		Covered[c.serialize()] = true
+	}
+	for _, Name := range v.Required {
+		c, err := parseComponent(Name)
+		if err != nil {
+			return err
+		}
+		if !Covered[c.serialize()] {
+			return fmt.Errorf("%w %s", ErrMissingComponent, c.serialize())
+		}
+	}
+
+	// Check the times.
+	Now := time.Now()
+	if v.Now != nil {
+		Now = v.Now()
+	}
+	if Created, ok := timeParam(Components.params, "created"); ok && v.MaxAge != 0 && Now.Sub(Created) > v.MaxAge {
+		return ErrExpired
+	}
+	if Expires, ok := timeParam(Components.params, "expires"); ok && Now.After(Expires) {
+		return ErrExpired
+	}
+
+	// Find the key.
+	KeyID, _ := Components.params.get("keyid")
+	Key, ok := KeyID.(string)
+	if !ok {
+		return ErrUnknownKey
+	}
+	Algorithm, ok := v.Keys[Key]
+	if !ok {
+		return ErrUnknownKey
+	}
+	if Alg, ok := Components.params.get("alg"); ok && Alg != Algorithm.Name() {
+		return ErrInvalidSignature
+	}
+
+	// Check the signature, then the body if the digest is covered.
+	Base, err := m.base(Components)
+	if err != nil {
+		return err
+	}
+	if err = Algorithm.Verify(Base, Signature); err != nil {
+		return err
+	}
+	if Covered[`"content-digest"`] {
+		return VerifyContentDigest(r)
+	}
+	return nil
+}
+
+// Handler wraps a handler so requests without a valid signature are rejected with 401 Unauthorized.
+func (v *Verifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,30 @@
+package structuredhttp
+
+import "context"
+
+// RoundTrip sends a request and returns the response.
+type RoundTrip func(ctx context.Context, r *Request) (*Response, error)
+
+// Middleware wraps a RoundTrip. The middleware can change the request before calling next, inspect or change the
+// response after, or return a response without calling next at all.
+type Middleware func(next RoundTrip) RoundTrip
+
+// Use adds middleware to the request. Middleware which is added first runs first.
+func (r *Request) Use(Middleware ...Middleware) *Request {
+	if r.Error != nil {
+		return r
+	}
+	r.Middleware = append(r.Middleware, Middleware...)
+	return r
+}
+
+// roundTrip is used to send the request once through the middleware chain.
+func (r *Request) roundTrip(ctx context.Context) (*Response, error) {
+	Next := RoundTrip(func(ctx context.Context, r *Request) (*Response, error) {
+		return r.do(ctx)
+	})
+	for i := len(r.Middleware) - 1; i >= 0; i-- {
+		Next = r.Middleware[i](Next)
+	}
+	return Next(ctx, r)
+}
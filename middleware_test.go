@@ -0,0 +1,70 @@
+package structuredhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Order")))
+	}))
+	defer server.Close()
+
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, r *Request) (*Response, error) {
+				order = append(order, name)
+				r.Headers["X-Order"] += name
+				res, err := next(ctx, r)
+				order = append(order, name)
+				return res, err
+			}
+		}
+	}
+
+	client := NewClient()
+	client.Middleware = []Middleware{mark("client")}
+	handler := RouteHandler{BaseURL: server.URL, Client: client, Middleware: []Middleware{mark("handler")}}
+	response, err := handler.GET("/").Use(mark("request")).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	text, err := response.Text()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if text != "clienthandlerrequest" {
+		t.Error("Invalid string returned (" + text + ").")
+		return
+	}
+	if strings.Join(order, ",") != "client,handler,request,request,handler,client" {
+		t.Error("Middleware ran in the wrong order (" + strings.Join(order, ",") + ").")
+		return
+	}
+	t.Log("Middleware works!")
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	cached := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			return &Response{RawResponse: &http.Response{StatusCode: http.StatusTeapot}}, nil
+		}
+	}
+	response, err := GET("http://127.0.0.1:0").Use(cached).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusTeapot {
+		t.Error("Expected the middleware response to be returned.")
+		return
+	}
+	t.Log("Middleware short-circuited the request.")
+}
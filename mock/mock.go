@@ -0,0 +1,356 @@
+// Package mock lets tests register expected requests and the responses to return for them, so code built on
+// structuredhttp can be tested without the network. The mock is a http.RoundTripper which never sends requests, so
+// the client from Client can be used by a RouteHandler, and the mock sees requests after all middleware has ran:
+//
+//	m := mock.New(t)
+//	m.Expect("POST", "/users").JSON(map[string]string{"name": "a"}).Reply(201).JSON(map[string]int{"id": 1})
+//	handler := structuredhttp.RouteHandler{BaseURL: "https://api.test", Client: m.Client()}
+//	...
+//	m.AssertExpectations()
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// ErrUnexpectedRequest is returned for requests which do not match any expectation.
+var ErrUnexpectedRequest = errors.New("mock: unexpected request")
+
+// TestingT is the part of testing.TB which is used to report failed assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Mock holds the expected requests.
+type Mock struct {
+	t            TestingT
+	lock         sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+// New creates a mock which reports failed assertions to the test specified.
+func New(t TestingT) *Mock {
+	return &Mock{t: t}
+}
+
+// Expect adds an expected request. The path can be a path, which matches requests to any host, or an absolute URL.
+// The query string is not matched unless Query is used.
+func (m *Mock) Expect(Method string, Path string) *Expectation {
+	e := &Expectation{mock: m, method: Method, path: Path, query: url.Values{}, headers: http.Header{}}
+	m.lock.Lock()
+	m.expectations = append(m.expectations, e)
+	m.lock.Unlock()
+	return e
+}
+
+// Client creates a client which uses the mock as its transport, so the mock sees the headers set by auth and
+// middleware. Transports are not used on WASM, so use Middleware there.
+func (m *Mock) Client() *structuredhttp.Client {
+	c := structuredhttp.NewClient()
+	c.Transport = m
+	return c
+}
+
+// AssertExpectations reports expectations which were called the wrong number of times and requests which did not
+// match an expectation. It returns false if there were any.
+func (m *Mock) AssertExpectations() bool {
+	m.t.Helper()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	Passed := true
+	for _, e := range m.expectations {
+		switch {
+		case e.times == 0 && e.calls == 0:
+			m.t.Errorf("mock: expected %s to be called", e)
+			Passed = false
+		case e.times != 0 && e.calls != e.times:
+			m.t.Errorf("mock: expected %s to be called %d times, but it was called %d times", e, e.times, e.calls)
+			Passed = false
+		}
+	}
+	for _, v := range m.unexpected {
+		m.t.Errorf("mock: unexpected request %s", v)
+		Passed = false
+	}
+	return Passed
+}
+
+// RoundTrip returns the response for an expected request. This lets the mock be used as the Transport of a client.
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var Body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		Body = b
+	}
+	Headers := make(map[string]string, len(req.Header))
+	for k := range req.Header {
+		Headers[k] = req.Header.Get(k)
+	}
+	r := &structuredhttp.Request{
+		URL:            req.URL.String(),
+		Method:         req.Method,
+		Headers:        Headers,
+		CurrentReader:  bytes.NewReader(Body),
+		CurrentContext: req.Context(),
+	}
+	Match, err := m.find(r, req.URL, Body)
+	if err != nil {
+		return nil, err
+	}
+	return Match.reply.response(req)
+}
+
+// Middleware is middleware which returns the responses for expected requests. It does not call the next round trip,
+// so middleware added after it does not run. Use Client where possible.
+func (m *Mock) Middleware(structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+	return func(ctx context.Context, r *structuredhttp.Request) (*structuredhttp.Response, error) {
+		Body, err := r.BodyBytes()
+		if err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, err
+		}
+		Match, err := m.find(r, u, Body)
+		if err != nil {
+			return nil, err
+		}
+		RawRequest, err := http.NewRequestWithContext(ctx, r.Method, r.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		RawResponse, err := Match.reply.response(RawRequest)
+		if err != nil {
+			return nil, err
+		}
+		return &structuredhttp.Response{RawResponse: RawResponse}, nil
+	}
+}
+
+// find is used to get the first expectation which matches a request, recording the call.
+func (m *Mock) find(r *structuredhttp.Request, u *url.URL, Body []byte) (*Expectation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, e := range m.expectations {
+		if e.matches(r, u, Body) {
+			e.calls++
+			return e, nil
+		}
+	}
+	m.unexpected = append(m.unexpected, r.Method+" "+r.URL)
+	return nil, fmt.Errorf("%w %s %s", ErrUnexpectedRequest, r.Method, r.URL)
+}
+
+// Expectation is an expected request.
+type Expectation struct {
+	mock    *Mock
+	method  string
+	path    string
+	query   url.Values
+	headers http.Header
+	body    func(b []byte) bool
+	match   func(r *structuredhttp.Request, Body []byte) bool
+	times   int
+	calls   int
+	reply   *Reply
+}
+
+// String returns the method and path of the expectation.
+func (e *Expectation) String() string {
+	return e.method + " " + e.path
+}
+
+// Query adds a query argument which the request must have.
+func (e *Expectation) Query(Key string, Value string) *Expectation {
+	e.query.Add(Key, Value)
+	return e
+}
+
+// Header adds a header which the request must have.
+func (e *Expectation) Header(Key string, Value string) *Expectation {
+	e.headers.Set(Key, Value)
+	return e
+}
+
+// Body sets the body the request must have.
+func (e *Expectation) Body(Data []byte) *Expectation {
+	e.body = func(b []byte) bool {
+		return bytes.Equal(b, Data)
+	}
+	return e
+}
+
+// JSON sets the JSON body the request must have. The bodies are compared after being decoded, so the formatting and
+// key order do not matter.
+func (e *Expectation) JSON(Data interface{}) *Expectation {
+	Expected, err := normaliseJSON(Data)
+	e.body = func(b []byte) bool {
+		var Actual interface{}
+		return err == nil && json.Unmarshal(b, &Actual) == nil && reflect.DeepEqual(Expected, Actual)
+	}
+	return e
+}
+
+// Match adds a function which must return true for the request to match.
+func (e *Expectation) Match(f func(r *structuredhttp.Request, Body []byte) bool) *Expectation {
+	e.match = f
+	return e
+}
+
+// Times sets the number of times the request is expected. Once it has been called this many times, it stops matching.
+// By default, the request must be called at least once and matches any number of times.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Calls returns the number of times the expectation has been called.
+func (e *Expectation) Calls() int {
+	e.mock.lock.Lock()
+	defer e.mock.lock.Unlock()
+	return e.calls
+}
+
+// Reply sets the status of the response to the request, returning the reply so the rest of it can be set.
+func (e *Expectation) Reply(StatusCode int) *Reply {
+	e.reply = &Reply{statusCode: StatusCode, headers: http.Header{}}
+	return e.reply
+}
+
+// Error makes the request fail with the error specified, like a network error.
+func (e *Expectation) Error(err error) {
+	e.reply = &Reply{err: err}
+}
+
+// matches is used to check if a request matches the expectation.
+func (e *Expectation) matches(r *structuredhttp.Request, u *url.URL, Body []byte) bool {
+	if e.times != 0 && e.calls >= e.times {
+		return false
+	}
+	if !strings.EqualFold(e.method, r.Method) {
+		return false
+	}
+	if strings.Contains(e.path, "://") {
+		Without := *u
+		Without.RawQuery = ""
+		if Without.String() != e.path {
+			return false
+		}
+	} else if u.Path != e.path {
+		return false
+	}
+	Query := u.Query()
+	for k, v := range e.query {
+		if !reflect.DeepEqual(Query[k], v) {
+			return false
+		}
+	}
+	if len(e.headers) != 0 {
+		h := http.Header{}
+		for k, v := range r.Headers {
+			h.Set(k, v)
+		}
+		for k := range e.headers {
+			if h.Get(k) != e.headers.Get(k) {
+				return false
+			}
+		}
+	}
+	if e.body != nil && !e.body(Body) {
+		return false
+	}
+	return e.match == nil || e.match(r, Body)
+}
+
+// Reply is the response to an expected request.
+type Reply struct {
+	statusCode int
+	headers    http.Header
+	body       []byte
+	err        error
+}
+
+// Header sets a header on the response.
+func (r *Reply) Header(Key string, Value string) *Reply {
+	r.headers.Set(Key, Value)
+	return r
+}
+
+// Body sets the body of the response.
+func (r *Reply) Body(Data []byte) *Reply {
+	r.body = Data
+	return r
+}
+
+// Text sets the body of the response to the text specified.
+func (r *Reply) Text(Data string) *Reply {
+	if r.headers.Get("Content-Type") == "" {
+		r.headers.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	return r.Body([]byte(Data))
+}
+
+// JSON sets the body of the response to the JSON specified.
+func (r *Reply) JSON(Data interface{}) *Reply {
+	b, err := json.Marshal(Data)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if r.headers.Get("Content-Type") == "" {
+		r.headers.Set("Content-Type", "application/json")
+	}
+	return r.Body(b)
+}
+
+// response is used to create the response for a request.
+func (r *Reply) response(req *http.Request) (*http.Response, error) {
+	if r == nil {
+		return nil, errors.New("mock: no reply was set for " + req.Method + " " + req.URL.String())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(r.statusCode) + " " + http.StatusText(r.statusCode),
+		StatusCode:    r.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.headers.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}, nil
+}
+
+// normaliseJSON is used to encode and decode a value so it can be compared to a decoded body.
+func normaliseJSON(Data interface{}) (interface{}, error) {
+	b, err := json.Marshal(Data)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
@@ -0,0 +1,128 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// recorder records the failures reported by a mock.
+type recorder struct {
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestMock(t *testing.T) {
+	m := New(t)
+	m.Expect("POST", "/users").JSON(map[string]string{"name": "a"}).Reply(http.StatusCreated).JSON(map[string]int{"id": 1})
+	m.Expect("GET", "/users/1").Query("fields", "name").Header("X-Token", "abc").Times(2).Reply(http.StatusOK).Text("a")
+
+	handler := structuredhttp.RouteHandler{
+		BaseURL: "https://api.test",
+		Headers: map[string]string{"X-Token": "abc"},
+		Client:  m.Client(),
+	}
+	user, err := structuredhttp.RunJSON[map[string]int](handler.POST("/users").JSON(map[string]string{"name": "a"}))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if user["id"] != 1 {
+		t.Error("Invalid body returned.")
+		return
+	}
+	for i := 0; i < 2; i++ {
+		response, err := handler.GET("/users/1").Query("fields", "name").Run()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if text, _ := response.Text(); text != "a" {
+			t.Error("Invalid body returned.")
+			return
+		}
+	}
+	m.AssertExpectations()
+}
+
+func TestMockFailures(t *testing.T) {
+	r := &recorder{}
+	m := New(r)
+	m.Expect("GET", "/called").Times(2).Reply(http.StatusOK)
+	m.Expect("GET", "/uncalled").Reply(http.StatusOK)
+	failure := errors.New("connection reset")
+	m.Expect("GET", "https://api.test/error").Error(failure)
+
+	client := m.Client()
+	if _, err := client.GET("https://api.test/called").Run(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if _, err := client.GET("https://api.test/error").Run(); !errors.Is(err, failure) {
+		t.Error("Expected the error reply, got", err)
+		return
+	}
+	if _, err := client.POST("https://api.test/called").Run(); !errors.Is(err, ErrUnexpectedRequest) {
+		t.Error("Expected the unexpected request to fail.")
+		return
+	}
+	if m.AssertExpectations() || len(r.failures) != 3 {
+		t.Error("Expected 3 failures, got", r.failures)
+		return
+	}
+	t.Log("Failures were reported.")
+}
+
+func TestMockAfterMiddleware(t *testing.T) {
+	m := New(t)
+	e := m.Expect("GET", "/signed").Header("Authorization", "Bearer tok").Header("X-Signature", "sig")
+	e.Reply(http.StatusOK)
+
+	// The mock must see the headers set by the token source and by middleware.
+	Sign := func(next structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+		return func(ctx context.Context, r *structuredhttp.Request) (*structuredhttp.Response, error) {
+			r.Headers["X-Signature"] = "sig"
+			return next(ctx, r)
+		}
+	}
+	handler := structuredhttp.RouteHandler{
+		BaseURL:     "https://api.test",
+		Client:      m.Client(),
+		TokenSource: structuredhttp.StaticTokenSource(&structuredhttp.Token{AccessToken: "tok"}),
+		Middleware:  []structuredhttp.Middleware{Sign},
+	}
+	Batch := structuredhttp.Batch{}
+	for i := 0; i < 5; i++ {
+		Batch = append(Batch, handler.GET("/signed"))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			_ = e.Calls()
+		}
+	}()
+	if _, err := Batch.All(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	<-done
+	if e.Calls() != 5 {
+		t.Error("Expected 5 calls, got", e.Calls())
+	}
+
+	// The middleware still works when it is the last middleware.
+	if _, err := structuredhttp.GET("https://api.test/signed").BearerToken("tok").Use(Sign, m.Middleware).Run(); err != nil {
+		t.Error(err.Error())
+	}
+	m.AssertExpectations()
+}
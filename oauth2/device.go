@@ -0,0 +1,87 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// ErrDeviceCodeExpired is returned when the device code expires before the user authorizes the device.
+var ErrDeviceCodeExpired = errors.New("oauth2: device code expired")
+
+// DeviceAuth is the response from the device authorization endpoint. The user should be shown the UserCode and
+// VerificationURI (or VerificationURIComplete, which includes the code).
+type DeviceAuth struct {
+	DeviceCode              string    `json:"device_code"`
+	UserCode                string    `json:"user_code"`
+	VerificationURI         string    `json:"verification_uri"`
+	VerificationURIComplete string    `json:"verification_uri_complete,omitempty"`
+	Expiry                  time.Time `json:"-"`
+
+	// Interval is how long to wait between polls of the token endpoint.
+	Interval time.Duration `json:"-"`
+}
+
+// deviceResponse is the JSON returned by the device authorization endpoint.
+type deviceResponse struct {
+	DeviceAuth
+	ExpiresIn int64 `json:"expires_in"`
+	Interval  int64 `json:"interval"`
+}
+
+// DeviceAuth starts the device code grant.
+func (c *Config) DeviceAuth(ctx context.Context) (*DeviceAuth, error) {
+	var res deviceResponse
+	if err := c.post(ctx, c.DeviceAuthURL, url.Values{"client_id": {c.ClientID}}, &res); err != nil {
+		return nil, err
+	}
+	Auth := res.DeviceAuth
+	if res.ExpiresIn > 0 {
+		Auth.Expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+	Auth.Interval = 5 * time.Second
+	if res.Interval > 0 {
+		Auth.Interval = time.Duration(res.Interval) * time.Second
+	}
+	return &Auth, nil
+}
+
+// DeviceToken polls the token endpoint until the user authorizes the device, the code expires, or the context is
+// done. If the server asks to slow down, the interval is increased by 5 seconds.
+func (c *Config) DeviceToken(ctx context.Context, Auth *DeviceAuth) (*Token, error) {
+	Interval := Auth.Interval
+	for {
+		// Wait for the interval or for the context to be done.
+		Timer := time.NewTimer(Interval)
+		select {
+		case <-ctx.Done():
+			Timer.Stop()
+			return nil, ctx.Err()
+		case <-Timer.C:
+		}
+		if !Auth.Expiry.IsZero() && time.Now().After(Auth.Expiry) {
+			return nil, ErrDeviceCodeExpired
+		}
+
+		// Poll the token endpoint.
+		t, err := c.Exchange(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {Auth.DeviceCode},
+			"client_id":   {c.ClientID},
+		})
+		var e *Error
+		if !errors.As(err, &e) {
+			return t, err
+		}
+		switch e.Code {
+		case "authorization_pending":
+		case "slow_down":
+			Interval += 5 * time.Second
+		case "expired_token":
+			return nil, ErrDeviceCodeExpired
+		default:
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package oauth2 implements the OAuth 2.0 client credentials, refresh token and device code grants on top of
+// structuredhttp. Each grant returns a *structuredhttp.CachedTokenSource, so tokens are cached until they expire, only
+// one request for a new token is made however many requests are waiting for it, and a token rejected with 401 is
+// refreshed once. The source can be set as the TokenSource of a Client or RouteHandler:
+//
+//	config := &oauth2.Config{ClientID: "id", ClientSecret: "secret", TokenURL: "https://auth.example.com/token"}
+//	handler := structuredhttp.RouteHandler{BaseURL: "https://api.example.com", TokenSource: config.ClientCredentials()}
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// AuthStyle is how the client ID and secret are sent to the token endpoint.
+type AuthStyle int
+
+const (
+	// AuthStyleInHeader sends the client ID and secret with basic authentication.
+	AuthStyleInHeader AuthStyle = iota
+
+	// AuthStyleInParams sends the client ID and secret in the form body.
+	AuthStyleInParams
+)
+
+// ErrNoRefreshToken is returned when a token needs refreshing but there is no refresh token.
+var ErrNoRefreshToken = errors.New("oauth2: token expired and no refresh token is set")
+
+// Error is an error returned by the authorization server.
+type Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	s := "oauth2: " + e.Code
+	if e.Description != "" {
+		s += ": " + e.Description
+	}
+	return s
+}
+
+// Token is a token returned by the authorization server.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Token converts the token to one which can be returned by a structuredhttp.TokenSource.
+func (t *Token) Token() *structuredhttp.Token {
+	return &structuredhttp.Token{AccessToken: t.AccessToken, TokenType: t.TokenType, Expiry: t.Expiry}
+}
+
+// Config is the configuration of a client of the authorization server.
+type Config struct {
+	ClientID      string
+	ClientSecret  string
+	TokenURL      string
+	DeviceAuthURL string
+	Scopes        []string
+	AuthStyle     AuthStyle
+
+	// EndpointParams are added to every request to the token endpoint, for example an audience.
+	EndpointParams url.Values
+
+	// Client is used to make requests to the authorization server. If this is nil, the default client is used.
+	Client *structuredhttp.Client
+
+	// OnToken is called with each new token. This can be used to store rotated refresh tokens.
+	OnToken func(t *Token)
+}
+
+// post is used to post a form to an endpoint and decode the JSON response into the pointer specified. Errors from the
+// server are returned as an *Error.
+func (c *Config) post(ctx context.Context, URL string, Form url.Values, Pointer interface{}) error {
+	for k, v := range c.EndpointParams {
+		Form[k] = v
+	}
+	if len(c.Scopes) != 0 && Form.Get("scope") == "" {
+		Form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	var req *structuredhttp.Request
+	if c.Client == nil {
+		req = structuredhttp.POST(URL)
+	} else {
+		req = c.Client.POST(URL)
+	}
+	if c.AuthStyle == AuthStyleInParams {
+		Form.Set("client_id", c.ClientID)
+		if c.ClientSecret != "" {
+			Form.Set("client_secret", c.ClientSecret)
+		}
+	} else {
+		req = req.BasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+	res, err := req.Header("Accept", "application/json").URLEncodedForm(Form).RunContext(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := res.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// Some servers respond with a form rather than JSON.
+	if MediaType, _, _ := mime.ParseMediaType(res.RawResponse.Header.Get("Content-Type")); MediaType == "application/x-www-form-urlencoded" {
+		Values, err := url.ParseQuery(string(b))
+		if err != nil {
+			return err
+		}
+		m := map[string]interface{}{}
+		for k := range Values {
+			m[k] = Values.Get(k)
+		}
+		if b, err = json.Marshal(m); err != nil {
+			return err
+		}
+	}
+
+	// Return the error if there is one.
+	if res.RawResponse.StatusCode >= 400 {
+		e := &Error{StatusCode: res.RawResponse.StatusCode}
+		if json.Unmarshal(b, e) != nil || e.Code == "" {
+			return res.RaiseForStatus()
+		}
+		return e
+	}
+	return json.Unmarshal(b, Pointer)
+}
+
+// tokenResponse is the JSON returned by the token endpoint.
+type tokenResponse struct {
+	AccessToken  string      `json:"access_token"`
+	TokenType    string      `json:"token_type"`
+	RefreshToken string      `json:"refresh_token"`
+	Scope        string      `json:"scope"`
+	ExpiresIn    json.Number `json:"expires_in"`
+}
+
+// Exchange requests a token from the token endpoint with the form specified, which must include the grant type. If a
+// refresh token was sent and the server does not return a new one, the token keeps the one which was sent.
+func (c *Config) Exchange(ctx context.Context, Form url.Values) (*Token, error) {
+	var res tokenResponse
+	if err := c.post(ctx, c.TokenURL, Form, &res); err != nil {
+		return nil, err
+	}
+	if res.AccessToken == "" {
+		return nil, errors.New("oauth2: server response is missing access_token")
+	}
+	t := &Token{
+		AccessToken:  res.AccessToken,
+		TokenType:    res.TokenType,
+		RefreshToken: res.RefreshToken,
+		Scope:        res.Scope,
+	}
+	if t.RefreshToken == "" {
+		t.RefreshToken = Form.Get("refresh_token")
+	}
+	if ExpiresIn, err := strconv.ParseInt(res.ExpiresIn.String(), 10, 64); err == nil && ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(ExpiresIn) * time.Second)
+	}
+	if c.OnToken != nil {
+		c.OnToken(t)
+	}
+	return t, nil
+}
+
+// ClientCredentials returns a token source which uses the client credentials grant.
+func (c *Config) ClientCredentials() *structuredhttp.CachedTokenSource {
+	return structuredhttp.ReuseTokenSource(structuredhttp.TokenSourceFunc(func(ctx context.Context) (*structuredhttp.Token, error) {
+		t, err := c.Exchange(ctx, url.Values{"grant_type": {"client_credentials"}})
+		if err != nil {
+			return nil, err
+		}
+		return t.Token(), nil
+	}))
+}
+
+// TokenSource returns a token source which starts with the token specified and uses its refresh token to get a new
+// one once it expires or is rejected. If the server rotates the refresh token, the new one is used from then on.
+func (c *Config) TokenSource(t *Token) *structuredhttp.CachedTokenSource {
+	Current := *t
+	First := true
+	return structuredhttp.ReuseTokenSource(structuredhttp.TokenSourceFunc(func(ctx context.Context) (*structuredhttp.Token, error) {
+		// The cache only asks for another token once the last one is no longer usable, so the starting token is only
+		// used the first time.
+		if First {
+			First = false
+			if Start := Current.Token(); Start.Valid() {
+				return Start, nil
+			}
+		}
+		if Current.RefreshToken == "" {
+			return nil, ErrNoRefreshToken
+		}
+		New, err := c.Exchange(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {Current.RefreshToken},
+		})
+		if err != nil {
+			return nil, err
+		}
+		Current = *New
+		return Current.Token(), nil
+	}))
+}
+
+// RefreshToken returns a token source which uses the refresh token grant.
+func (c *Config) RefreshToken(RefreshToken string) *structuredhttp.CachedTokenSource {
+	return c.TokenSource(&Token{RefreshToken: RefreshToken})
+}
@@ -0,0 +1,157 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// newServer creates an authorization server and an API which accepts the latest access token.
+func newServer(t *testing.T) (*httptest.Server, *int32) {
+	var issued int32
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id, secret, _ := r.BasicAuth()
+		if id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		switch r.PostFormValue("grant_type") {
+		case "client_credentials":
+		case "refresh_token":
+			if r.PostFormValue("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if polls++; polls < 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "authorization_pending"}`))
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		n := atomic.AddInt32(&issued, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  strconv.Itoa(int(n)),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": "refresh",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"device_code": "dc", "user_code": "ABCD", "verification_uri": "https://example.com", "expires_in": 60}`))
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+strconv.Itoa(int(atomic.LoadInt32(&issued))) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestClientCredentials(t *testing.T) {
+	server, issued := newServer(t)
+	config := &Config{ClientID: "id", ClientSecret: "secret", TokenURL: server.URL + "/token"}
+	handler := structuredhttp.RouteHandler{BaseURL: server.URL, TokenSource: config.ClientCredentials()}
+	var batch structuredhttp.Batch
+	for i := 0; i < 10; i++ {
+		batch = append(batch, handler.GET("/api"))
+	}
+	responses, err := batch.All()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	for _, response := range responses {
+		if err = response.RaiseForStatus(); err != nil {
+			t.Error(err.Error())
+			return
+		}
+	}
+	if *issued != 1 {
+		t.Error("Expected one token to be issued, got", *issued)
+	}
+
+	// Errors from the server are returned as an *Error.
+	config.ClientSecret = "wrong"
+	_, err = config.ClientCredentials().Token(context.Background())
+	if e, ok := err.(*Error); !ok || e.Code != "invalid_client" || e.StatusCode != 401 {
+		t.Error("Expected invalid_client, got", err)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	server, issued := newServer(t)
+	var stored *Token
+	lock := sync.Mutex{}
+	config := &Config{
+		ClientID: "id", ClientSecret: "secret", TokenURL: server.URL + "/token",
+		OnToken: func(t *Token) {
+			lock.Lock()
+			stored = t
+			lock.Unlock()
+		},
+	}
+
+	// A token the API no longer accepts is refreshed after the 401.
+	atomic.StoreInt32(issued, 5)
+	source := config.TokenSource(&Token{AccessToken: "old", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)})
+	handler := structuredhttp.RouteHandler{BaseURL: server.URL, TokenSource: source}
+	response, err := handler.GET("/api").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if err = response.RaiseForStatus(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if stored == nil || stored.AccessToken != "6" || stored.RefreshToken != "refresh" {
+		t.Error("Expected the new token to be stored, got", stored)
+	}
+
+	// An invalid refresh token returns an error.
+	_, err = config.RefreshToken("wrong").Token(context.Background())
+	if e, ok := err.(*Error); !ok || e.Code != "invalid_grant" {
+		t.Error("Expected invalid_grant, got", err)
+	}
+}
+
+func TestDeviceCode(t *testing.T) {
+	server, _ := newServer(t)
+	config := &Config{ClientID: "id", ClientSecret: "secret", TokenURL: server.URL + "/token", DeviceAuthURL: server.URL + "/device"}
+	auth, err := config.DeviceAuth(context.Background())
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if auth.UserCode != "ABCD" || auth.Interval != 5*time.Second {
+		t.Error("Unexpected device auth:", auth)
+		return
+	}
+	auth.Interval = 10 * time.Millisecond
+	token, err := config.DeviceToken(context.Background(), auth)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if token.AccessToken != "1" {
+		t.Error("Expected the token after the user authorized the device, got", token)
+	}
+}
@@ -0,0 +1,146 @@
+package structuredhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxySelector chooses the proxy for a request. Returning a nil URL connects directly. Proxy URLs can use the http,
+// https, socks5 or socks5h schemes, and credentials in the URL are used for proxy authentication.
+type ProxySelector func(Request *http.Request) (*url.URL, error)
+
+// proxyContextKey is the context key for the proxy choice of a request.
+type proxyContextKey struct{}
+
+// proxyChoice is the proxy choice of a request.
+type proxyChoice struct {
+	URL      *url.URL
+	Selector ProxySelector
+}
+
+// withProxy is used to add the proxy choice of the request to the context. True is returned if it was added.
+func (r *Request) withProxy(ctx context.Context) (context.Context, bool) {
+	var Selector ProxySelector
+	if r.CurrentClient != nil {
+		Selector = r.CurrentClient.ProxySelector
+	}
+	if r.CurrentProxy == nil && Selector == nil {
+		return ctx, false
+	}
+	return context.WithValue(ctx, proxyContextKey{}, &proxyChoice{URL: r.CurrentProxy, Selector: Selector}), true
+}
+
+// proxyFromContext is used as the Proxy function of transports. The proxy set on the request is used first, then the
+// selector of the client, then the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func proxyFromContext(req *http.Request) (*url.URL, error) {
+	if Choice, ok := req.Context().Value(proxyContextKey{}).(*proxyChoice); ok {
+		if Choice.URL != nil {
+			if Choice.URL.Host == "" {
+				return nil, nil
+			}
+			return Choice.URL, nil
+		}
+		if Choice.Selector != nil {
+			return Choice.Selector(req)
+		}
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// parseProxyURL is used to parse a proxy URL. URLs without a scheme use http.
+func parseProxyURL(URL string) (*url.URL, error) {
+	if !strings.Contains(URL, "://") {
+		URL = "http://" + URL
+	}
+	return url.Parse(URL)
+}
+
+// noProxyMatch is used to check if a host and port match a NO_PROXY rule. Rules can be "*", a domain which also
+// matches its subdomains (with or without a leading dot), an IP address, a CIDR range, and any of these with a port.
+func noProxyMatch(Rule, Host, Port string) bool {
+	Rule = strings.ToLower(strings.TrimSpace(Rule))
+	if Rule == "" {
+		return false
+	}
+	if Rule == "*" {
+		return true
+	}
+	if _, Network, err := net.ParseCIDR(Rule); err == nil {
+		IP := net.ParseIP(Host)
+		return IP != nil && Network.Contains(IP)
+	}
+	if RuleHost, RulePort, err := net.SplitHostPort(Rule); err == nil {
+		if RulePort != Port {
+			return false
+		}
+		Rule = RuleHost
+	}
+	if RuleIP := net.ParseIP(Rule); RuleIP != nil {
+		return RuleIP.Equal(net.ParseIP(Host))
+	}
+	Rule = strings.TrimPrefix(strings.TrimPrefix(Rule, "*"), ".")
+	return Host == Rule || strings.HasSuffix(Host, "."+Rule)
+}
+
+// ProxyURL returns a selector which sends every request through the proxy specified, except for hosts which match
+// one of the NoProxy rules. Rules are in the same format as the NO_PROXY environment variable, and may also be given
+// as a single comma separated string.
+func ProxyURL(URL string, NoProxy ...string) (ProxySelector, error) {
+	u, err := parseProxyURL(URL)
+	if err != nil {
+		return nil, err
+	}
+	var Rules []string
+	for _, v := range NoProxy {
+		Rules = append(Rules, strings.Split(v, ",")...)
+	}
+	return func(Request *http.Request) (*url.URL, error) {
+		Host := strings.ToLower(Request.URL.Hostname())
+		Port := Request.URL.Port()
+		if Port == "" {
+			Port = "80"
+			if Request.URL.Scheme == "https" {
+				Port = "443"
+			}
+		}
+		for _, v := range Rules {
+			if noProxyMatch(v, Host, Port) {
+				return nil, nil
+			}
+		}
+		return u, nil
+	}, nil
+}
+
+// SetProxy sends requests made with the client through the proxy specified, except for hosts which match one of the
+// NoProxy rules. See ProxyURL.
+func (c *Client) SetProxy(URL string, NoProxy ...string) error {
+	Selector, err := ProxyURL(URL, NoProxy...)
+	if err != nil {
+		return err
+	}
+	c.ProxySelector = Selector
+	return nil
+}
+
+// Proxy sends this request through the proxy specified, overriding the proxy of the client. An empty URL connects
+// directly. This has no effect on requests made with a client whose transport is not from NewClient.
+func (r *Request) Proxy(URL string) *Request {
+	if r.Error != nil {
+		return r
+	}
+	if URL == "" {
+		r.CurrentProxy = &url.URL{}
+		return r
+	}
+	u, err := parseProxyURL(URL)
+	if err != nil {
+		r.Error = &err
+		return r
+	}
+	r.CurrentProxy = u
+	return r
+}
@@ -0,0 +1,205 @@
+package structuredhttp
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newHTTPProxy creates a proxy which forwards plain requests and tunnels CONNECT requests. It requires the
+// credentials user:pass.
+func newHTTPProxy(t *testing.T) (*httptest.Server, *int32) {
+	var used int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		atomic.AddInt32(&used, 1)
+		if r.Method == http.MethodConnect {
+			upstream, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			go func() { _, _ = io.Copy(upstream, conn) }()
+			_, _ = io.Copy(conn, upstream)
+			_ = conn.Close()
+			return
+		}
+		r.RequestURI = ""
+		r.Header.Del("Proxy-Authorization")
+		res, err := (&http.Transport{}).RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+		w.Header().Set("X-Proxied", "true")
+		w.WriteHeader(res.StatusCode)
+		_, _ = io.Copy(w, res.Body)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &used
+}
+
+// newSOCKS5Proxy creates a SOCKS5 proxy which requires the credentials user:pass.
+func newSOCKS5Proxy(t *testing.T) (net.Listener, *int32) {
+	var used int32
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				b := make([]byte, 512)
+
+				// Choose username/password authentication.
+				if _, err := io.ReadFull(conn, b[:2]); err != nil {
+					return
+				}
+				_, _ = io.ReadFull(conn, b[:b[1]])
+				_, _ = conn.Write([]byte{5, 2})
+				_, _ = io.ReadFull(conn, b[:2])
+				user := make([]byte, b[1])
+				_, _ = io.ReadFull(conn, user)
+				_, _ = io.ReadFull(conn, b[:1])
+				pass := make([]byte, b[0])
+				_, _ = io.ReadFull(conn, pass)
+				if string(user) != "user" || string(pass) != "pass" {
+					_, _ = conn.Write([]byte{1, 1})
+					return
+				}
+				_, _ = conn.Write([]byte{1, 0})
+
+				// Read the connect request.
+				_, _ = io.ReadFull(conn, b[:4])
+				var host string
+				switch b[3] {
+				case 1:
+					_, _ = io.ReadFull(conn, b[:4])
+					host = net.IP(b[:4]).String()
+				case 3:
+					_, _ = io.ReadFull(conn, b[:1])
+					name := make([]byte, b[0])
+					_, _ = io.ReadFull(conn, name)
+					host = string(name)
+				default:
+					return
+				}
+				_, _ = io.ReadFull(conn, b[:2])
+				upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(b[:2])))))
+				if err != nil {
+					_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer upstream.Close()
+				atomic.AddInt32(&used, 1)
+				_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go func() { _, _ = io.Copy(upstream, conn) }()
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return listener, &used
+}
+
+func TestProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	proxy, proxyUsed := newHTTPProxy(t)
+	socks, socksUsed := newSOCKS5Proxy(t)
+	proxyURL := "http://user:pass@" + proxy.Listener.Addr().String()
+
+	// Plain requests are forwarded by the client's proxy.
+	client := tlsClient(t, tlsServer)
+	if err := client.SetProxy(proxyURL); err != nil {
+		t.Fatal(err)
+	}
+	handler := RouteHandler{BaseURL: server.URL, Client: client}
+	response, err := handler.GET("/").Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.RawResponse.Header.Get("X-Proxied") != "true" {
+		t.Error("Expected the request to go through the proxy.")
+	}
+
+	// HTTPS requests are tunnelled with CONNECT.
+	if _, err = client.GET(tlsServer.URL).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if *proxyUsed != 2 {
+		t.Error("Expected the proxy to be used twice, got", *proxyUsed)
+	}
+
+	// The proxy can be changed for a single request, including without a client.
+	if _, err = client.GET(tlsServer.URL).Proxy("socks5://user:pass@" + socks.Addr().String()).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GET(server.URL).Proxy("socks5://user:pass@" + socks.Addr().String()).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if *socksUsed != 2 || *proxyUsed != 2 {
+		t.Error("Expected the SOCKS5 proxy to be used twice, got", *socksUsed, *proxyUsed)
+	}
+
+	// Hosts matching NO_PROXY rules connect directly.
+	_ = client.SetProxy(proxyURL, "example.com,127.0.0.0/8")
+	if _, err = handler.GET("/").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if *proxyUsed != 2 {
+		t.Error("Expected the proxy to be skipped, got", *proxyUsed)
+	}
+
+	// Wrong credentials are rejected by the proxy.
+	_ = client.SetProxy("http://user:wrong@" + proxy.Listener.Addr().String())
+	response, err = handler.GET("/").Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.RawResponse.StatusCode != http.StatusProxyAuthRequired {
+		t.Error("Expected 407, got", response.RawResponse.StatusCode)
+	}
+}
+
+func TestNoProxyMatch(t *testing.T) {
+	tests := []struct {
+		rule, host, port string
+		match            bool
+	}{
+		{"*", "example.com", "80", true},
+		{"example.com", "example.com", "80", true},
+		{"example.com", "api.example.com", "443", true},
+		{".example.com", "api.example.com", "443", true},
+		{"*.example.com", "api.example.com", "443", true},
+		{"example.com", "badexample.com", "80", false},
+		{"example.com:8080", "example.com", "80", false},
+		{"example.com:8080", "example.com", "8080", true},
+		{"10.0.0.0/8", "10.1.2.3", "80", true},
+		{"10.0.0.0/8", "11.1.2.3", "80", false},
+		{"::1", "::1", "80", true},
+	}
+	for _, test := range tests {
+		if noProxyMatch(test.rule, test.host, test.port) != test.match {
+			t.Error("Expected", test.rule, "matching", test.host+":"+test.port, "to be", test.match)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package structuredhttp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ErrTooManyRedirects is returned when a request is redirected more times than the redirect policy allows.
+var ErrTooManyRedirects = errors.New("structuredhttp: too many redirects")
+
+// Redirect is a response which redirected the request.
+type Redirect struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// RedirectPolicy defines how redirects are followed. 307 and 308 redirects always keep the method and body, and other
+// redirects change the method to GET (or keep HEAD) unless PreserveMethod is true.
+type RedirectPolicy struct {
+	// MaxRedirects is the most redirects which are followed before ErrTooManyRedirects is returned. This defaults to
+	// 10.
+	MaxRedirects int `json:"max_redirects"`
+
+	// Disable stops redirects being followed, so the redirect response is returned.
+	Disable bool `json:"disable"`
+
+	// SameHost only follows redirects to the same host. Redirects to other hosts are returned.
+	SameHost bool `json:"same_host"`
+
+	// KeepAuthorization keeps the Authorization header when redirected to a different origin. By default, it is
+	// removed so credentials are not sent to another server.
+	KeepAuthorization bool `json:"keep_authorization"`
+
+	// PreserveMethod keeps the method and body for every redirect, not just 307 and 308.
+	PreserveMethod bool `json:"preserve_method"`
+}
+
+// Redirects sets the redirect policy of the request. On WASM, the browser follows redirects, so only Disable is used.
+func (r *Request) Redirects(Policy RedirectPolicy) *Request {
+	if r.Error != nil {
+		return r
+	}
+	r.CurrentRedirects = &Policy
+	return r
+}
+
+// redirectPolicy is used to get the redirect policy of the request, or the default policy if it is not set.
+func (r *Request) redirectPolicy() *RedirectPolicy {
+	if r.CurrentRedirects != nil {
+		return r.CurrentRedirects
+	}
+	return &RedirectPolicy{}
+}
+
+// origin is used to get the scheme, host and port of a request.
+func origin(req *http.Request) string {
+	Port := req.URL.Port()
+	if Port == "" {
+		Port = "80"
+		if req.URL.Scheme == "https" {
+			Port = "443"
+		}
+	}
+	return req.URL.Scheme + "://" + strings.ToLower(req.URL.Hostname()) + ":" + Port
+}
+
+// checkRedirect is used to get the CheckRedirect function of a HTTP client for the policy. Each redirect is added to
+// the history.
+func (p *RedirectPolicy) checkRedirect(History *[]Redirect) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		Last := via[len(via)-1]
+		*History = append(*History, Redirect{
+			Method:     Last.Method,
+			URL:        Last.URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+		})
+
+		// Check if the redirect is allowed.
+		if p.Disable || p.SameHost && !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+			*History = (*History)[:len(*History)-1]
+			return http.ErrUseLastResponse
+		}
+		Max := p.MaxRedirects
+		if Max == 0 {
+			Max = 10
+		}
+		if len(via) > Max {
+			return ErrTooManyRedirects
+		}
+
+		// Keep the method and body if the policy says to.
+		if p.PreserveMethod && req.Method != Last.Method {
+			req.Method = Last.Method
+			if Last.GetBody != nil {
+				Body, err := Last.GetBody()
+				if err != nil {
+					return err
+				}
+				req.Body, req.GetBody, req.ContentLength = Body, Last.GetBody, Last.ContentLength
+			}
+		}
+
+		// Remove credentials when changing origin. The HTTP client already removes them when the host changes, so they
+		// are copied back if they should be kept.
+		if p.KeepAuthorization {
+			if Authorization := via[0].Header.Values("Authorization"); len(Authorization) != 0 {
+				req.Header["Authorization"] = Authorization
+			}
+		} else if origin(req) != origin(via[0]) {
+			req.Header.Del("Authorization")
+		}
+		return nil
+	}
+}
+
+// getBody is used to get the GetBody function of a HTTP request from a body which can be replayed, so it can be sent
+// again when redirected. Nil is returned if the body cannot be replayed.
+func (r *Request) getBody() func() (io.ReadCloser, error) {
+	Body, err := r.replayableBody(false)
+	if err != nil {
+		return nil
+	}
+	Reader, err := Body()
+	if err != nil {
+		return nil
+	}
+	r.CurrentReader = Reader
+	return func() (io.ReadCloser, error) {
+		Reader, err := Body()
+		if err != nil {
+			return nil, err
+		}
+		if Reader == nil {
+			return http.NoBody, nil
+		}
+		return ioutil.NopCloser(Reader), nil
+	}
+}
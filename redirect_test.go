@@ -0,0 +1,196 @@
+package structuredhttp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(r.Method))
+		}
+	}))
+	defer server.Close()
+
+	response, err := GET(server.URL + "/a").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if text, _ := response.Text(); text != "GET" {
+		t.Error("Invalid response (" + text + ").")
+	}
+	if len(response.History) != 2 {
+		t.Errorf("Expected 2 redirects, got %d.", len(response.History))
+		return
+	}
+	if h := response.History[0]; h.URL != server.URL+"/a" || h.StatusCode != 301 || h.Location != "/b" || h.Method != "GET" {
+		t.Errorf("Invalid first redirect (%+v).", h)
+	}
+	if h := response.History[1]; h.URL != server.URL+"/b" || h.StatusCode != 302 || h.Location != "/c" {
+		t.Errorf("Invalid second redirect (%+v).", h)
+	}
+}
+
+func TestRedirectMax(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := GET(server.URL).Redirects(RedirectPolicy{MaxRedirects: 3}).Run()
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Expected ErrTooManyRedirects, got %v.", err)
+	}
+}
+
+func TestRedirectDisable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/other", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Redirects = &RedirectPolicy{Disable: true}
+	response, err := client.GET(server.URL).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusFound || response.RawResponse.Header.Get("Location") != "/other" {
+		t.Errorf("Expected the redirect response, got %d.", response.RawResponse.StatusCode)
+	}
+	if len(response.History) != 0 {
+		t.Error("Expected no redirects in the history.")
+	}
+}
+
+func TestRedirectSameHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("The redirect to another host was followed.")
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/same", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	response, err := GET(server.URL).Redirects(RedirectPolicy{SameHost: true}).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if response.RawResponse.StatusCode != http.StatusFound {
+		t.Errorf("Expected the redirect response, got %d.", response.RawResponse.StatusCode)
+	}
+	if len(response.History) != 1 || response.History[0].URL != server.URL {
+		t.Errorf("Expected one redirect in the history (%+v).", response.History)
+	}
+}
+
+func TestRedirectAuthorization(t *testing.T) {
+	var header string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/port" {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	// Redirect to another port, and to another host.
+	for _, path := range []string{"/port", "/host"} {
+		for _, keep := range []bool{false, true} {
+			header = ""
+			_, err := GET(server.URL+path).Header("Authorization", "Bearer secret").
+				Redirects(RedirectPolicy{KeepAuthorization: keep}).Run()
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+			if keep && header != "Bearer secret" {
+				t.Error("The Authorization header was removed for " + path + " (" + header + ").")
+			} else if !keep && header != "" {
+				t.Error("The Authorization header was sent to another origin for " + path + ".")
+			}
+		}
+	}
+}
+
+func TestRedirectAuthorizationSameOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	response, err := GET(server.URL).Header("Authorization", "Bearer secret").Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if text, _ := response.Text(); text != "Bearer secret" {
+		t.Error("The Authorization header was removed for the same origin (" + text + ").")
+	}
+}
+
+func TestRedirectPreserveMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/307":
+			http.Redirect(w, r, "/done", http.StatusTemporaryRedirect)
+		case "/302":
+			http.Redirect(w, r, "/done", http.StatusFound)
+		default:
+			b, _ := ioutil.ReadAll(r.Body)
+			_, _ = w.Write([]byte(r.Method + " " + string(b)))
+		}
+	}))
+	defer server.Close()
+
+	// The reader is wrapped so only its Seek function can be used to replay it.
+	tests := []struct {
+		path     string
+		policy   RedirectPolicy
+		expected string
+	}{
+		{"/307", RedirectPolicy{}, "POST hello"},
+		{"/302", RedirectPolicy{}, "GET "},
+		{"/302", RedirectPolicy{PreserveMethod: true}, "POST hello"},
+	}
+	for _, v := range tests {
+		Reader := struct{ io.ReadSeeker }{strings.NewReader("hello")}
+		response, err := POST(server.URL+v.path).Reader(Reader).Header("Content-Length", "5").
+			Redirects(v.policy).Run()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if text, _ := response.Text(); text != v.expected {
+			t.Error("Expected " + v.expected + " for " + v.path + ", got " + text + ".")
+		}
+	}
+}
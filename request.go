@@ -2,8 +2,10 @@ package structuredhttp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"strconv"
 	"strings"
@@ -12,12 +14,21 @@ import (
 
 // Request defines the request that will be ran.
 type Request struct {
-	URL            string            `json:"url"`
-	Method         string            `json:"method"`
-	Headers        map[string]string `json:"headers"`
-	CurrentTimeout *time.Duration    `json:"timeout"`
-	CurrentReader  io.Reader         `json:"-"`
-	Error          *error            `json:"-"`
+	URL              string            `json:"url"`
+	Method           string            `json:"method"`
+	Headers          map[string]string `json:"headers"`
+	CurrentTimeout   *time.Duration    `json:"timeout"`
+	CurrentReader    io.Reader         `json:"-"`
+	CurrentContext   context.Context   `json:"-"`
+	CurrentClient    *Client           `json:"-"`
+	CurrentRetry     *RetryPolicy      `json:"retry"`
+	CurrentProxy     *url.URL          `json:"-"`
+	CurrentDialer    Dialer            `json:"-"`
+	CurrentRedirects *RedirectPolicy   `json:"redirects"`
+	Middleware       []Middleware      `json:"-"`
+	Error            *error            `json:"-"`
+
+	digestCache *digestCache
 }
 
 // Header sets a header.
@@ -38,6 +49,15 @@ func (r *Request) Timeout(Value time.Duration) *Request {
 	return r
 }
 
+// Context sets the context which is used by Run. Cancelling the context aborts the request.
+func (r *Request) Context(ctx context.Context) *Request {
+	if r.Error != nil {
+		return r
+	}
+	r.CurrentContext = ctx
+	return r
+}
+
 // Bytes sets the data to the bytes specified.
 func (r *Request) Bytes(Data []byte) *Request {
 	if r.Error != nil {
@@ -118,6 +138,56 @@ func (r *Request) MultipartForm(Buffer *bytes.Buffer, ContentType string) *Reque
 	return r
 }
 
+// replayableBody is used to get a function which returns a reader over the body from where it currently is each
+// time it is called, so that the request can be sent again. In-memory bodies and files can always be replayed. Other
+// readers are buffered into memory if Buffer is true, otherwise ErrBodyNotRewindable is returned.
+func (r *Request) replayableBody(Buffer bool) (func() (io.Reader, error), error) {
+	switch x := r.CurrentReader.(type) {
+	case nil:
+		return func() (io.Reader, error) { return nil, nil }, nil
+	case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+	case io.Seeker:
+		Offset, err := x.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		return func() (io.Reader, error) {
+			_, err := x.Seek(Offset, io.SeekStart)
+			return r.CurrentReader, err
+		}, nil
+	default:
+		if !Buffer {
+			return nil, ErrBodyNotRewindable
+		}
+	}
+	b, err := ioutil.ReadAll(r.CurrentReader)
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.Reader, error) {
+		return bytes.NewReader(b), nil
+	}, nil
+}
+
+// BodyBytes returns the request body without consuming it, so the request can still be sent. Bodies from readers
+// which cannot be replayed are buffered into memory.
+func (r *Request) BodyBytes() ([]byte, error) {
+	Body, err := r.replayableBody(true)
+	if err != nil {
+		return nil, err
+	}
+	Reader, err := Body()
+	if err != nil || Reader == nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(Reader)
+	if err != nil {
+		return nil, err
+	}
+	r.CurrentReader, err = Body()
+	return b, err
+}
+
 // Plugin allows for third party functions to be chained into the request.
 func (r *Request) Plugin(Function func(r *Request)) *Request {
 	if r.Error != nil {
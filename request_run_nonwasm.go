@@ -3,41 +3,70 @@
 package structuredhttp
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// Run executes the request.
-func (r *Request) Run() (*Response, error) {
-	if r.Error != nil {
-		return nil, *r.Error
+// httpClient is used to get the HTTP client which is used to run a request.
+func (c *Client) httpClient(Timeout time.Duration) *http.Client {
+	Client := &http.Client{Timeout: Timeout}
+	if c != nil {
+		Client.Transport = c.Transport
+		Client.Jar = c.Jar
 	}
+	return Client
+}
+
+// do is used to send the request once.
+func (r *Request) do(ctx context.Context) (*Response, error) {
 	var CurrentTimeout time.Duration
 	if r.CurrentTimeout == nil {
 		CurrentTimeout = DefaultTimeout
 	} else {
 		CurrentTimeout = *r.CurrentTimeout
 	}
-	Client := http.Client{
-		Timeout: CurrentTimeout,
+	ctx, Options := r.withTransportOptions(ctx)
+	Client := r.CurrentClient.httpClient(CurrentTimeout)
+	if Client.Transport == nil && Options {
+		Client.Transport = defaultContextTransport()
 	}
+	var History []Redirect
+	Client.CheckRedirect = r.redirectPolicy().checkRedirect(&History)
+	GetBody := r.getBody()
 	Reader := r.CurrentReader
 	if Reader == nil {
 		Reader = strings.NewReader("")
 	}
-	RawRequest, err := http.NewRequest(r.Method, r.URL, Reader)
+	RawRequest, err := http.NewRequestWithContext(ctx, r.Method, r.URL, Reader)
 	if err != nil {
 		return nil, err
 	}
 	for k, v := range r.Headers {
 		RawRequest.Header.Set(k, v)
 	}
+	if RawRequest.GetBody == nil && RawRequest.Body != http.NoBody {
+		RawRequest.GetBody = GetBody
+	}
+	if Host := RawRequest.Header.Get("Host"); Host != "" {
+		RawRequest.Host = Host
+	}
+
+	// Use the Content-Length header for readers where the length cannot be worked out, rather than sending the body
+	// chunked.
+	if RawRequest.ContentLength == 0 && RawRequest.Body != http.NoBody {
+		if Length, err := strconv.ParseInt(RawRequest.Header.Get("Content-Length"), 10, 64); err == nil {
+			RawRequest.ContentLength = Length
+		}
+	}
 	RawResponse, err := Client.Do(RawRequest)
 	if err != nil {
 		return nil, err
 	}
 	return &Response{
 		RawResponse: RawResponse,
+		History:     History,
 	}, nil
 }
@@ -3,6 +3,7 @@
 package structuredhttp
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -70,15 +71,30 @@ func fetch(URL string, Args map[string]interface{}) (js.Value, error) {
 	return promiseHack(js.Global().Call("fetch", URL, obj))
 }
 
-func createSignal(ms int64) js.Value {
+func createSignal(ctx context.Context, ms int64) (js.Value, func()) {
 	// Create a instance of AbortController.
 	controller := js.Global().Get("AbortController").New()
+	abort := controller.Get("abort").Call("bind", controller)
 
-	// Run the setTimeout API on the controller abort function.
-	js.Global().Call("setTimeout", controller.Get("abort"), ms)
+	// Run the setTimeout API on the controller abort function if there is a timeout.
+	if ms != 0 {
+		js.Global().Call("setTimeout", abort, ms)
+	}
+
+	// Abort the controller if the context is done before the request is released.
+	released := make(chan struct{})
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				abort.Invoke()
+			case <-released:
+			}
+		}()
+	}
 
-	// Return the controllers signal.
-	return controller.Get("signal")
+	// Return the controllers signal and the release function.
+	return controller.Get("signal"), func() { close(released) }
 }
 
 func createReadableStream(r io.Reader) js.Value {
@@ -167,20 +183,34 @@ func fetch2http(fetch js.Value) *http.Response {
 	}
 }
 
-// Run executes the request.
-func (r *Request) Run() (*Response, error) {
-	// Handle previous errors.
-	if r.Error != nil {
-		return nil, *r.Error
+// credentials is used to get the fetch credentials mode for requests made with the client.
+func (c *Client) credentials() Credentials {
+	switch {
+	case c == nil:
+		return CredentialsSameOrigin
+	case c.Credentials != "":
+		return c.Credentials
+	case c.Jar != nil:
+		return CredentialsInclude
+	default:
+		return CredentialsSameOrigin
+	}
+}
+
+// do is used to send the request once.
+func (r *Request) do(ctx context.Context) (*Response, error) {
+	// Do not start the request if the context is already done.
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Create the AbortController signal if needed.
-	Signal := js.Undefined()
-	if r.CurrentTimeout == nil && DefaultTimeout != 0 {
-		Signal = createSignal(DefaultTimeout.Milliseconds())
-	} else if r.CurrentTimeout != nil && *r.CurrentTimeout != 0 {
-		Signal = createSignal(r.CurrentTimeout.Milliseconds())
+	// Create the AbortController signal.
+	CurrentTimeout := DefaultTimeout
+	if r.CurrentTimeout != nil {
+		CurrentTimeout = *r.CurrentTimeout
 	}
+	Signal, release := createSignal(ctx, CurrentTimeout.Milliseconds())
+	defer release()
 
 	// Defines the fetch arguments.
 	Reader := r.CurrentReader
@@ -191,7 +221,12 @@ func (r *Request) Run() (*Response, error) {
 		"signal": Signal,
 		"method": r.Method,
 		"headers": strmap2obj(r.Headers),
-		"body": createReadableStream(r.CurrentReader),
+		"body": createReadableStream(Reader),
+		"credentials": string(r.CurrentClient.credentials()),
+		"redirect": "follow",
+	}
+	if r.redirectPolicy().Disable {
+		FetchArgs["redirect"] = "manual"
 	}
 	if r.Method == "GET" || r.Method == "HEAD" {
 		delete(FetchArgs, "body")
@@ -200,9 +235,17 @@ func (r *Request) Run() (*Response, error) {
 	// Call fetch.
 	res, err := fetch(r.URL, FetchArgs)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
 
 	// Create the response object.
-	return &Response{RawResponse: fetch2http(res)}, nil
+	RawResponse := fetch2http(res)
+	RawResponse.Request, err = http.NewRequestWithContext(ctx, r.Method, res.Get("url").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{RawResponse: RawResponse}, nil
 }
@@ -2,16 +2,16 @@ package structuredhttp
 
 import (
 	"encoding/json"
-	"errors"
 	"io/ioutil"
-	"math"
 	"net/http"
-	"strconv"
 )
 
 // Response defines the higher level HTTP response.
 type Response struct {
 	RawResponse *http.Response
+
+	// History holds each redirect which was followed to get this response, in order. This is empty on WASM.
+	History []Redirect
 }
 
 // Parser is a response body parser. These can be found in the data package.
@@ -63,11 +63,10 @@ func (r *Response) JSONToPointer(Pointer interface{}) error {
 	return nil
 }
 
-// RaiseForStatus throws a error if the request is a 4XX/5XX.
+// RaiseForStatus throws a error if the request is a 4XX/5XX. The error is a *HTTPError.
 func (r *Response) RaiseForStatus() error {
-	FirstDigitStatus := math.Floor(float64(r.RawResponse.StatusCode) / 100)
-	if FirstDigitStatus == 4 || FirstDigitStatus == 5 {
-		return errors.New("returned the status " + strconv.Itoa(r.RawResponse.StatusCode))
+	if r.RawResponse.StatusCode >= 400 && r.RawResponse.StatusCode < 600 {
+		return newHTTPError(r.RawResponse)
 	}
 	return nil
 }
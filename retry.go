@@ -0,0 +1,169 @@
+package structuredhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrBodyNotRewindable is returned when a request body can only be read once and the request may need to be sent
+// again. Set BufferBody on the RetryPolicy to buffer these bodies into memory instead.
+var ErrBodyNotRewindable = errors.New("structuredhttp: the request body cannot be rewound to be sent again")
+
+// DefaultRetryStatusCodes defines the status codes which are retried if a RetryPolicy does not specify any.
+var DefaultRetryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooEarly,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy defines how a request is retried when there is a network error or a retryable status code.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the request is sent, including the first. 0 or 1 disables retrying.
+	MaxAttempts int `json:"max_attempts"`
+
+	// StatusCodes are the status codes which are retried. If this is nil, DefaultRetryStatusCodes is used.
+	StatusCodes []int `json:"status_codes"`
+
+	// BaseDelay is the delay before the first retry which is doubled for each retry after. Defaults to 100ms.
+	BaseDelay time.Duration `json:"base_delay"`
+
+	// MaxDelay caps the delay between attempts. Defaults to 30 seconds. If a Retry-After header asks for longer
+	// than this, the response is returned instead of being retried.
+	MaxDelay time.Duration `json:"max_delay"`
+
+	// DisableJitter stops the delay from being randomised. By default, a random half of the delay is used.
+	DisableJitter bool `json:"disable_jitter"`
+
+	// BufferBody allows bodies from one-shot readers to be buffered into memory so they can be sent again.
+	BufferBody bool `json:"buffer_body"`
+}
+
+// Retry sets the retry policy for the request.
+func (r *Request) Retry(Policy RetryPolicy) *Request {
+	if r.Error != nil {
+		return r
+	}
+	r.CurrentRetry = &Policy
+	return r
+}
+
+// retryable is used to check if a status code should be retried.
+func (p *RetryPolicy) retryable(StatusCode int) bool {
+	StatusCodes := p.StatusCodes
+	if StatusCodes == nil {
+		StatusCodes = DefaultRetryStatusCodes
+	}
+	for _, v := range StatusCodes {
+		if v == StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff is used to get the delay before the attempt specified (starting at 1 for the first retry).
+func (p *RetryPolicy) backoff(Attempt int) time.Duration {
+	BaseDelay := p.BaseDelay
+	if BaseDelay <= 0 {
+		BaseDelay = 100 * time.Millisecond
+	}
+	Delay := p.maxDelay()
+	if Attempt < 32 && BaseDelay<<uint(Attempt-1) < Delay && BaseDelay<<uint(Attempt-1) > 0 {
+		Delay = BaseDelay << uint(Attempt-1)
+	}
+	if !p.DisableJitter && Delay > 1 {
+		Delay = Delay/2 + time.Duration(rand.Int63n(int64(Delay/2)))
+	}
+	return Delay
+}
+
+// maxDelay is used to get the maximum delay with the default applied.
+func (p *RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return 30 * time.Second
+	}
+	return p.MaxDelay
+}
+
+// retryAfter is used to parse the Retry-After header on a 429 or 503 response. A negative duration is returned if
+// there is no usable header.
+func retryAfter(Response *http.Response) time.Duration {
+	if Response.StatusCode != http.StatusTooManyRequests && Response.StatusCode != http.StatusServiceUnavailable {
+		return -1
+	}
+	Header := Response.Header.Get("Retry-After")
+	if Header == "" {
+		return -1
+	}
+	if Seconds, err := strconv.Atoi(Header); err == nil {
+		if Seconds < 0 {
+			return -1
+		}
+		return time.Duration(Seconds) * time.Second
+	}
+	if When, err := http.ParseTime(Header); err == nil {
+		Delay := time.Until(When)
+		if Delay < 0 {
+			Delay = 0
+		}
+		return Delay
+	}
+	return -1
+}
+
+// run is used to run the request with this retry policy.
+func (p *RetryPolicy) run(ctx context.Context, r *Request) (*Response, error) {
+	Body, err := r.replayableBody(p.BufferBody)
+	if err != nil {
+		return nil, err
+	}
+	for Attempt := 1; ; Attempt++ {
+		// Set the body from the start.
+		if r.CurrentReader, err = Body(); err != nil {
+			return nil, err
+		}
+
+		// Send the request. Errors from the context being done are not retried.
+		res, err := r.roundTrip(ctx)
+		if ctx.Err() != nil || Attempt >= p.MaxAttempts {
+			return res, err
+		}
+
+		// Work out how long to wait before the next attempt.
+		Delay := p.backoff(Attempt)
+		if err == nil {
+			if !p.retryable(res.RawResponse.StatusCode) {
+				return res, nil
+			}
+			if After := retryAfter(res.RawResponse); After >= 0 {
+				if After > p.maxDelay() {
+					return res, nil
+				}
+				Delay = After
+			}
+
+			// Drain and close the body so the connection can be reused.
+			_, _ = io.Copy(ioutil.Discard, res.RawResponse.Body)
+			_ = res.RawResponse.Body.Close()
+		}
+
+		// Wait for the delay or for the context to be done.
+		Timer := time.NewTimer(Delay)
+		select {
+		case <-ctx.Done():
+			Timer.Stop()
+			return nil, ctx.Err()
+		case <-Timer.C:
+		}
+	}
+}
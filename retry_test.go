@@ -0,0 +1,57 @@
+package structuredhttp
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != `{"hello":"world"}` {
+			t.Error("Invalid body sent (" + string(b) + ").")
+		}
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+
+	Retry := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	handler := RouteHandler{BaseURL: server.URL, Retry: &Retry}
+	response, err := handler.POST("/").JSON(map[string]string{"hello": "world"}).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	err = response.RaiseForStatus()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if attempts != 3 {
+		t.Error("Expected 3 attempts, got", attempts)
+		return
+	}
+	t.Log("Request was retried.")
+}
+
+func TestRetryOneShotBody(t *testing.T) {
+	_, err := POST("http://127.0.0.1:0").Reader(ioutil.NopCloser(strings.NewReader("hello"))).Retry(
+		RetryPolicy{MaxAttempts: 2}).Run()
+	if !errors.Is(err, ErrBodyNotRewindable) {
+		t.Error("Expected the body to be refused, got", err)
+		return
+	}
+	t.Log("One-shot body was refused.")
+}
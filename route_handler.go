@@ -8,54 +8,58 @@ import (
 
 // RouteHandler defines the base HTTP URL/timeout which is used for routes.
 type RouteHandler struct {
-	BaseURL string            `json:"base_url"`
-	Timeout *time.Duration    `json:"-"`
-	Headers map[string]string `json:"headers"`
+	BaseURL     string            `json:"base_url"`
+	Timeout     *time.Duration    `json:"-"`
+	Headers     map[string]string `json:"headers"`
+	Client      *Client           `json:"-"`
+	Retry       *RetryPolicy      `json:"retry"`
+	Middleware  []Middleware      `json:"-"`
+	TokenSource TokenSource       `json:"-"`
+	Proxy       string            `json:"proxy,omitempty"`
+	Dialer      Dialer            `json:"-"`
+	Redirects   *RedirectPolicy   `json:"redirects"`
+
+	digest *digestCache
 }
 
-// GenerateURL takes a path and returns the URL with the path added.
+// GenerateURL takes a path and returns the URL with the path added. If the base URL is a Unix socket, the URL is an
+// HTTP URL with a host which is unique to the socket.
 func (r *RouteHandler) GenerateURL(Path string) (string, error) {
 	u, err := url.Parse(r.BaseURL)
 	if err != nil {
 		return "", err
 	}
+	if Socket, ok := unixSocket(u); ok {
+		u = &url.URL{Scheme: "http", Host: unixHost(Socket)}
+	}
 	u.Path = strings.TrimRight(u.Path, "/") + Path
 	return u.String(), nil
 }
 
-// GET does a GET request based on this base.
-func (r *RouteHandler) GET(Path string) *Request {
-	url, err := r.GenerateURL(Path)
-	if err != nil {
-		url = ""
-	}
-	req := GET(url)
+// socket is used to get the path of the Unix socket if the base URL is unix:///path or unix:@name.
+func (r *RouteHandler) socket() (string, bool) {
+	u, err := url.Parse(r.BaseURL)
 	if err != nil {
-		req.Error = &err
-		return req
+		return "", false
 	}
-	if r.Timeout != nil {
-		req = req.Timeout(*r.Timeout)
-	}
-	if r.Headers != nil {
-		for k, v := range r.Headers {
-			req = req.Header(k, v)
-		}
-	}
-	return req
+	return unixSocket(u)
 }
 
-// POST does a POST request based on this base.
-func (r *RouteHandler) POST(Path string) *Request {
+// request is used to create a request for the method and path specified.
+func (r *RouteHandler) request(Method func(URL string) *Request, Path string) *Request {
 	url, err := r.GenerateURL(Path)
 	if err != nil {
 		url = ""
 	}
-	req := POST(url)
+	req := Method(url)
 	if err != nil {
 		req.Error = &err
 		return req
 	}
+	req.digestCache = r.digestCache()
+	if r.Client != nil {
+		req = r.Client.defaults(req)
+	}
 	if r.Timeout != nil {
 		req = req.Timeout(*r.Timeout)
 	}
@@ -64,115 +68,68 @@ func (r *RouteHandler) POST(Path string) *Request {
 			req = req.Header(k, v)
 		}
 	}
-	return req
-}
-
-// PUT does a PUT request based on this base.
-func (r *RouteHandler) PUT(Path string) *Request {
-	url, err := r.GenerateURL(Path)
-	if err != nil {
-		url = ""
+	if r.Retry != nil {
+		req = req.Retry(*r.Retry)
 	}
-	req := PUT(url)
-	if err != nil {
-		req.Error = &err
-		return req
+	if r.Redirects != nil {
+		req = req.Redirects(*r.Redirects)
 	}
-	if r.Timeout != nil {
-		req = req.Timeout(*r.Timeout)
+	if r.Proxy != "" {
+		req = req.Proxy(r.Proxy)
 	}
-	if r.Headers != nil {
-		for k, v := range r.Headers {
-			req = req.Header(k, v)
+	if r.Dialer != nil {
+		req = req.Dial(r.Dialer)
+	}
+	if Socket, ok := r.socket(); ok {
+		// Connect directly, so requests to the socket never go through a proxy from the environment or the client.
+		req = req.Dial(UnixDialer(Socket)).Proxy("").Header("Host", "localhost")
+	}
+
+	// Add the token source before the middleware, so the Authorization header is set before any middleware runs. The
+	// token source of the route handler is used instead of the client's if both are set.
+	Source := r.TokenSource
+	if r.Client != nil {
+		if Source == nil {
+			Source = r.Client.TokenSource
 		}
+		req = r.Client.use(req, Source)
+	} else if Source != nil {
+		req = req.Auth(Source)
 	}
-	return req
+	return req.Use(r.Middleware...)
+}
+
+// GET does a GET request based on this base.
+func (r *RouteHandler) GET(Path string) *Request {
+	return r.request(GET, Path)
+}
+
+// POST does a POST request based on this base.
+func (r *RouteHandler) POST(Path string) *Request {
+	return r.request(POST, Path)
+}
+
+// PUT does a PUT request based on this base.
+func (r *RouteHandler) PUT(Path string) *Request {
+	return r.request(PUT, Path)
 }
 
 // PATCH does a PATCH request based on this base.
 func (r *RouteHandler) PATCH(Path string) *Request {
-	url, err := r.GenerateURL(Path)
-	if err != nil {
-		url = ""
-	}
-	req := PATCH(url)
-	if err != nil {
-		req.Error = &err
-		return req
-	}
-	if r.Timeout != nil {
-		req = req.Timeout(*r.Timeout)
-	}
-	if r.Headers != nil {
-		for k, v := range r.Headers {
-			req = req.Header(k, v)
-		}
-	}
-	return req
+	return r.request(PATCH, Path)
 }
 
 // DELETE does a DELETE request based on this base.
 func (r *RouteHandler) DELETE(Path string) *Request {
-	url, err := r.GenerateURL(Path)
-	if err != nil {
-		url = ""
-	}
-	req := DELETE(url)
-	if err != nil {
-		req.Error = &err
-		return req
-	}
-	if r.Timeout != nil {
-		req = req.Timeout(*r.Timeout)
-	}
-	if r.Headers != nil {
-		for k, v := range r.Headers {
-			req = req.Header(k, v)
-		}
-	}
-	return req
+	return r.request(DELETE, Path)
 }
 
 // OPTIONS does a OPTIONS request based on this base.
 func (r *RouteHandler) OPTIONS(Path string) *Request {
-	url, err := r.GenerateURL(Path)
-	if err != nil {
-		url = ""
-	}
-	req := OPTIONS(url)
-	if err != nil {
-		req.Error = &err
-		return req
-	}
-	if r.Timeout != nil {
-		req = req.Timeout(*r.Timeout)
-	}
-	if r.Headers != nil {
-		for k, v := range r.Headers {
-			req = req.Header(k, v)
-		}
-	}
-	return req
+	return r.request(OPTIONS, Path)
 }
 
 // HEAD does a HEAD request based on this base.
 func (r *RouteHandler) HEAD(Path string) *Request {
-	url, err := r.GenerateURL(Path)
-	if err != nil {
-		url = ""
-	}
-	req := HEAD(url)
-	if err != nil {
-		req.Error = &err
-		return req
-	}
-	if r.Timeout != nil {
-		req = req.Timeout(*r.Timeout)
-	}
-	if r.Headers != nil {
-		for k, v := range r.Headers {
-			req = req.Header(k, v)
-		}
-	}
-	return req
+	return r.request(HEAD, Path)
 }
@@ -0,0 +1,23 @@
+package structuredhttp
+
+import "context"
+
+// Run executes the request using the context set with Context, or a background context if there is none.
+func (r *Request) Run() (*Response, error) {
+	ctx := r.CurrentContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.RunContext(ctx)
+}
+
+// RunContext executes the request with the context specified.
+func (r *Request) RunContext(ctx context.Context) (*Response, error) {
+	if r.Error != nil {
+		return nil, *r.Error
+	}
+	if r.CurrentRetry != nil && r.CurrentRetry.MaxAttempts > 1 {
+		return r.CurrentRetry.run(ctx, r)
+	}
+	return r.roundTrip(ctx)
+}
@@ -0,0 +1,89 @@
+package sigv4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chunkSignatureLength is the length of ";chunk-signature=" followed by a signature.
+const chunkSignatureLength = len(";chunk-signature=") + 64
+
+// chunkSize is used to get the size of each chunk.
+func (s *Signer) chunkSize() int {
+	if s.ChunkSize <= 0 {
+		return 64 * 1024
+	}
+	return s.ChunkSize
+}
+
+// encodedLength is used to get the length of a body once it has been signed in chunks, including the final empty
+// chunk.
+func (s *Signer) encodedLength(Length int64) int64 {
+	Size := int64(s.chunkSize())
+	chunk := func(n int64) int64 {
+		return int64(len(strconv.FormatInt(n, 16))+chunkSignatureLength+2) + n + 2
+	}
+	Encoded := (Length/Size)*chunk(Size) + chunk(0)
+	if Remainder := Length % Size; Remainder != 0 {
+		Encoded += chunk(Remainder)
+	}
+	return Encoded
+}
+
+// chunkReader signs a body in chunks as it is read. Each chunk is signed with the signature of the one before it,
+// starting with the signature of the request.
+type chunkReader struct {
+	s        *Signer
+	r        io.Reader
+	key      []byte
+	now      time.Time
+	previous string
+	buf      bytes.Buffer
+	chunk    []byte
+	done     bool
+}
+
+// newChunkReader is used to create a reader which signs the body in chunks.
+func (s *Signer) newChunkReader(r io.Reader, Seed string, Now time.Time) io.Reader {
+	return &chunkReader{s: s, r: r, key: s.signingKey(Now), now: Now, previous: Seed, chunk: make([]byte, s.chunkSize())}
+}
+
+// writeChunk is used to sign a chunk and write it to the buffer.
+func (c *chunkReader) writeChunk(Data []byte) {
+	StringToSign := strings.Join([]string{
+		Algorithm + "-PAYLOAD",
+		c.now.Format(timeFormat),
+		c.s.scope(c.now),
+		c.previous,
+		emptyHash,
+		hashHex(Data),
+	}, "\n")
+	c.previous = hex.EncodeToString(hmacSHA256(c.key, StringToSign))
+	c.buf.WriteString(strconv.FormatInt(int64(len(Data)), 16) + ";chunk-signature=" + c.previous + "\r\n")
+	c.buf.Write(Data)
+	c.buf.WriteString("\r\n")
+}
+
+// Read implements io.Reader.
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for c.buf.Len() == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(c.r, c.chunk)
+		switch err {
+		case nil, io.ErrUnexpectedEOF:
+			c.writeChunk(c.chunk[:n])
+		case io.EOF:
+			c.writeChunk(nil)
+			c.done = true
+		default:
+			return 0, err
+		}
+	}
+	return c.buf.Read(p)
+}
@@ -0,0 +1,355 @@
+// Package sigv4 signs structuredhttp requests with AWS Signature Version 4, for AWS services and S3-compatible
+// storage such as MinIO. Sign requests once the headers and body are set, either with the Plugin chain step or by
+// adding the Middleware to a request, Client or RouteHandler so each attempt is signed when it is sent:
+//
+//	signer := &sigv4.Signer{Credentials: sigv4.EnvCredentials(), Region: "us-east-1", Service: "s3"}
+//	response, err := structuredhttp.PUT(URL).Bytes(b).Use(signer.Middleware).Run()
+package sigv4
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+const (
+	// Algorithm is the signing algorithm.
+	Algorithm = "AWS4-HMAC-SHA256"
+
+	// UnsignedPayload is the payload hash used when the body is not signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// StreamingPayload is the payload hash used when the body is signed in chunks.
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+	// timeFormat is the format of X-Amz-Date.
+	timeFormat = "20060102T150405Z"
+)
+
+// ErrUnknownContentLength is returned when a body needs to be signed in chunks but the Content-Length header is not set.
+var ErrUnknownContentLength = errors.New("sigv4: streaming a body requires the Content-Length header")
+
+// emptyHash is the hash of an empty payload.
+var emptyHash = hashHex(nil)
+
+// ignoredHeaders are headers which are not signed since they may be changed on the way to the server.
+var ignoredHeaders = map[string]bool{
+	"authorization":     true,
+	"user-agent":        true,
+	"expect":            true,
+	"x-amzn-trace-id":   true,
+	"transfer-encoding": true,
+}
+
+// Credentials are the AWS credentials used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// EnvCredentials gets the credentials from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables.
+func EnvCredentials() Credentials {
+	return Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Signer signs requests.
+type Signer struct {
+	Credentials Credentials
+	Region      string
+	Service     string
+
+	// UnsignedPayload sends UNSIGNED-PAYLOAD rather than hashing the body. This is only supported by some services,
+	// such as S3.
+	UnsignedPayload bool
+
+	// ChunkSize is the size of each chunk when a body which cannot be replayed is signed in chunks. This defaults to
+	// 64KiB.
+	ChunkSize int
+
+	// Now returns the time the request is signed at. This defaults to time.Now.
+	Now func() time.Time
+}
+
+// now is used to get the time to sign at.
+func (s *Signer) now() time.Time {
+	if s.Now == nil {
+		return time.Now().UTC()
+	}
+	return s.Now().UTC()
+}
+
+// hashHex is used to get the hex encoded SHA-256 of the data specified.
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// hmacSHA256 is used to get the HMAC-SHA256 of the data specified.
+func hmacSHA256(Key []byte, Data string) []byte {
+	h := hmac.New(sha256.New, Key)
+	_, _ = h.Write([]byte(Data))
+	return h.Sum(nil)
+}
+
+// escape is used to URI encode a string as AWS expects, leaving only unreserved characters as they are.
+func escape(s string, EncodeSlash bool) string {
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' || c == '/' && !EncodeSlash {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+// scope is used to get the credential scope for the date specified.
+func (s *Signer) scope(Now time.Time) string {
+	return Now.Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+// signingKey is used to derive the signing key for the date specified.
+func (s *Signer) signingKey(Now time.Time) []byte {
+	Key := hmacSHA256([]byte("AWS4"+s.Credentials.SecretAccessKey), Now.Format("20060102"))
+	Key = hmacSHA256(Key, s.Region)
+	Key = hmacSHA256(Key, s.Service)
+	return hmacSHA256(Key, "aws4_request")
+}
+
+// canonicalURI is used to get the canonical path. S3 paths are used as they are, other services have the path
+// normalized and encoded twice.
+func (s *Signer) canonicalURI(u *url.URL) string {
+	Path := u.Path
+	if Path == "" {
+		return "/"
+	}
+	if s.Service == "s3" {
+		return escape(Path, false)
+	}
+	Clean := path.Clean(Path)
+	if strings.HasSuffix(Path, "/") && Clean != "/" {
+		Clean += "/"
+	}
+	return escape(escape(Clean, false), false)
+}
+
+// canonicalQuery is used to get the encoded query string, sorted by name and then value. The pairs are sorted before
+// they are joined, otherwise a name which is a prefix of another (such as a and a1) would sort after it.
+func canonicalQuery(Query url.Values) string {
+	Pairs := [][2]string{}
+	for k, v := range Query {
+		for _, Value := range v {
+			Pairs = append(Pairs, [2]string{escape(k, true), escape(Value, true)})
+		}
+	}
+	sort.Slice(Pairs, func(i, j int) bool {
+		if Pairs[i][0] != Pairs[j][0] {
+			return Pairs[i][0] < Pairs[j][0]
+		}
+		return Pairs[i][1] < Pairs[j][1]
+	})
+	Joined := make([]string, len(Pairs))
+	for i, v := range Pairs {
+		Joined[i] = v[0] + "=" + v[1]
+	}
+	return strings.Join(Joined, "&")
+}
+
+// canonicalHeaders is used to get the canonical headers and the list of signed headers.
+func canonicalHeaders(Host string, Headers map[string]string) (string, string) {
+	Values := map[string]string{"host": Host}
+	for k, v := range Headers {
+		Name := strings.ToLower(k)
+		if ignoredHeaders[Name] || Name == "host" {
+			continue
+		}
+		Values[Name] = strings.Join(strings.Fields(v), " ")
+	}
+	Names := make([]string, 0, len(Values))
+	for k := range Values {
+		Names = append(Names, k)
+	}
+	sort.Strings(Names)
+	b := strings.Builder{}
+	for _, v := range Names {
+		b.WriteString(v + ":" + Values[v] + "\n")
+	}
+	return b.String(), strings.Join(Names, ";")
+}
+
+// signature is used to sign the canonical request, returning the signature and the signed headers.
+func (s *Signer) signature(Method string, u *url.URL, Query url.Values, Headers map[string]string, PayloadHash string, Now time.Time) (string, string) {
+	Canonical, Signed := canonicalHeaders(u.Host, Headers)
+	Request := strings.Join([]string{
+		Method,
+		s.canonicalURI(u),
+		canonicalQuery(Query),
+		Canonical,
+		Signed,
+		PayloadHash,
+	}, "\n")
+	StringToSign := strings.Join([]string{
+		Algorithm,
+		Now.Format(timeFormat),
+		s.scope(Now),
+		hashHex([]byte(Request)),
+	}, "\n")
+	return hex.EncodeToString(hmacSHA256(s.signingKey(Now), StringToSign)), Signed
+}
+
+// setHeader is used to set a header, replacing it if it was set with different casing.
+func setHeader(Headers map[string]string, Key, Value string) {
+	for k := range Headers {
+		if strings.EqualFold(k, Key) {
+			delete(Headers, k)
+		}
+	}
+	Headers[Key] = Value
+}
+
+// getHeader is used to get a header regardless of casing.
+func getHeader(Headers map[string]string, Key string) string {
+	for k, v := range Headers {
+		if strings.EqualFold(k, Key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// payloadHash is used to work out the payload hash. Bodies which can be replayed are hashed, other bodies are signed
+// in chunks.
+func (s *Signer) payloadHash(r *structuredhttp.Request) (string, error) {
+	if s.UnsignedPayload {
+		return UnsignedPayload, nil
+	}
+	switch r.CurrentReader.(type) {
+	case nil:
+		return emptyHash, nil
+	case *bytes.Buffer, io.Seeker:
+		b, err := r.BodyBytes()
+		if err != nil {
+			return "", err
+		}
+		return hashHex(b), nil
+	default:
+		return StreamingPayload, nil
+	}
+}
+
+// Sign signs the request as it currently is. The X-Amz-Date, X-Amz-Security-Token, X-Amz-Content-Sha256 and
+// Authorization headers are set. The X-Amz-Content-Sha256 header is only set for S3 or when the payload is not
+// hashed, since other services do not expect it.
+func (s *Signer) Sign(r *structuredhttp.Request) error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	if r.Headers == nil {
+		r.Headers = map[string]string{}
+	}
+	Now := s.now()
+	PayloadHash, err := s.payloadHash(r)
+	if err != nil {
+		return err
+	}
+
+	// Work out the lengths of a body which is signed in chunks.
+	var Length int64
+	if PayloadHash == StreamingPayload {
+		if Length, err = strconv.ParseInt(getHeader(r.Headers, "Content-Length"), 10, 64); err != nil {
+			return ErrUnknownContentLength
+		}
+		setHeader(r.Headers, "Content-Encoding", "aws-chunked")
+		setHeader(r.Headers, "X-Amz-Decoded-Content-Length", strconv.FormatInt(Length, 10))
+		setHeader(r.Headers, "Content-Length", strconv.FormatInt(s.encodedLength(Length), 10))
+	}
+
+	// Set the headers and sign.
+	setHeader(r.Headers, "X-Amz-Date", Now.Format(timeFormat))
+	if s.Credentials.SessionToken != "" {
+		setHeader(r.Headers, "X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+	if s.Service == "s3" || PayloadHash == UnsignedPayload || PayloadHash == StreamingPayload {
+		setHeader(r.Headers, "X-Amz-Content-Sha256", PayloadHash)
+	}
+	Signature, Signed := s.signature(r.Method, u, u.Query(), r.Headers, PayloadHash, Now)
+	setHeader(r.Headers, "Authorization", Algorithm+" Credential="+s.Credentials.AccessKeyID+"/"+s.scope(Now)+
+		", SignedHeaders="+Signed+", Signature="+Signature)
+	if PayloadHash == StreamingPayload {
+		r.CurrentReader = s.newChunkReader(r.CurrentReader, Signature, Now)
+	}
+	return nil
+}
+
+// Plugin signs the request when it is chained with Plugin. Errors are set on the request.
+func (s *Signer) Plugin(r *structuredhttp.Request) {
+	if err := s.Sign(r); err != nil {
+		r.Error = &err
+	}
+}
+
+// Middleware signs a copy of the request each time it is sent, so retries are signed again with the current time.
+func (s *Signer) Middleware(next structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+	return func(ctx context.Context, r *structuredhttp.Request) (*structuredhttp.Response, error) {
+		Copy := *r
+		Copy.Headers = make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			Copy.Headers[k] = v
+		}
+		if err := s.Sign(&Copy); err != nil {
+			return nil, err
+		}
+		return next(ctx, &Copy)
+	}
+}
+
+// Presign returns the URL of the request with the signature in the query string, which is valid for the duration
+// specified. Only the host header is signed. S3 URLs are signed with UNSIGNED-PAYLOAD.
+func (s *Signer) Presign(r *structuredhttp.Request, Expires time.Duration) (string, error) {
+	if r.Error != nil {
+		return "", *r.Error
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return "", err
+	}
+	Now := s.now()
+	Query := u.Query()
+	Query.Set("X-Amz-Algorithm", Algorithm)
+	Query.Set("X-Amz-Credential", s.Credentials.AccessKeyID+"/"+s.scope(Now))
+	Query.Set("X-Amz-Date", Now.Format(timeFormat))
+	Query.Set("X-Amz-Expires", strconv.FormatInt(int64(Expires/time.Second), 10))
+	Query.Set("X-Amz-SignedHeaders", "host")
+	if s.Credentials.SessionToken != "" {
+		Query.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+	PayloadHash := emptyHash
+	if s.Service == "s3" {
+		PayloadHash = UnsignedPayload
+	}
+	Signature, _ := s.signature(r.Method, u, Query, nil, PayloadHash, Now)
+	u.RawQuery = canonicalQuery(Query) + "&X-Amz-Signature=" + Signature
+	return u.String(), nil
+}
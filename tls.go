@@ -0,0 +1,156 @@
+package structuredhttp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ErrNoTLSTransport is returned when the TLS options of a client are set but its transport is not a *http.Transport.
+var ErrNoTLSTransport = errors.New("structuredhttp: the client transport is not a *http.Transport")
+
+// ErrNoCertificates is returned when a PEM file does not contain any certificates.
+var ErrNoCertificates = errors.New("structuredhttp: no certificates found in PEM data")
+
+// PinMismatchError is returned when none of the certificates the server sent match a pinned public key.
+type PinMismatchError struct {
+	Host     string
+	Expected []string
+	Got      []string
+}
+
+// Error implements the error interface.
+func (e *PinMismatchError) Error() string {
+	return "structuredhttp: public key pin mismatch for " + e.Host + " (expected one of " +
+		strings.Join(e.Expected, ", ") + ", got " + strings.Join(e.Got, ", ") + ")"
+}
+
+// PublicKeyPin returns the pin of a certificate's public key, which is "sha256/" followed by the base64 encoded
+// SHA-256 of its subject public key info. This is the same format as curl's --pinnedpubkey.
+func PublicKeyPin(Certificate *x509.Certificate) string {
+	h := sha256.Sum256(Certificate.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(h[:])
+}
+
+// TLSConfig returns the TLS configuration of the client's transport so it can be changed, creating it if it is not
+// set. Connections which are already open keep the configuration they were made with.
+//
+// TLS options are only set on clients, not on single requests. Connections are pooled by the transport, so a request
+// with its own options could reuse a connection which was made without them (skipping a pin, for example). Use a
+// separate client for requests which need different options.
+func (c *Client) TLSConfig() (*tls.Config, error) {
+	Transport, ok := c.Transport.(*http.Transport)
+	if !ok {
+		return nil, ErrNoTLSTransport
+	}
+	if Transport.TLSClientConfig == nil {
+		Transport.TLSClientConfig = &tls.Config{}
+	}
+	return Transport.TLSClientConfig, nil
+}
+
+// ClientCertificate adds a client certificate for mutual TLS from PEM encoded certificate and key files.
+func (c *Client) ClientCertificate(CertFile, KeyFile string) error {
+	Config, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	Certificate, err := tls.LoadX509KeyPair(CertFile, KeyFile)
+	if err != nil {
+		return err
+	}
+	Config.Certificates = append(Config.Certificates, Certificate)
+	return nil
+}
+
+// RootCAs sets the certificate authorities which are trusted to the PEM encoded certificates specified, rather than
+// the system pool.
+func (c *Client) RootCAs(PEM ...[]byte) error {
+	Config, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	Pool := x509.NewCertPool()
+	for _, v := range PEM {
+		if !Pool.AppendCertsFromPEM(v) {
+			return ErrNoCertificates
+		}
+	}
+	Config.RootCAs = Pool
+	return nil
+}
+
+// RootCAFiles sets the certificate authorities which are trusted to the certificates in the PEM files specified.
+func (c *Client) RootCAFiles(Files ...string) error {
+	PEM := make([][]byte, len(Files))
+	for i, v := range Files {
+		b, err := ioutil.ReadFile(v)
+		if err != nil {
+			return err
+		}
+		PEM[i] = b
+	}
+	return c.RootCAs(PEM...)
+}
+
+// MinTLSVersion sets the minimum TLS version, such as tls.VersionTLS12.
+func (c *Client) MinTLSVersion(Version uint16) error {
+	Config, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	Config.MinVersion = Version
+	return nil
+}
+
+// ServerName sets the server name sent with SNI and checked against the certificate, rather than the host of the URL.
+func (c *Client) ServerName(Name string) error {
+	Config, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	Config.ServerName = Name
+	return nil
+}
+
+// PinPublicKeys only allows connections to servers whose verified certificate chain has one of the public keys
+// specified, in the format returned by PublicKeyPin. Certificates the server sent which are not part of the verified
+// chain are ignored. If InsecureSkipVerify is set, there is no verified chain, so only the leaf certificate is checked.
+// If none match, the request returns a *PinMismatchError.
+func (c *Client) PinPublicKeys(Pins ...string) error {
+	Config, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	Allowed := map[string]bool{}
+	for _, v := range Pins {
+		Allowed[v] = true
+	}
+	Config.VerifyConnection = func(State tls.ConnectionState) error {
+		Chains := State.VerifiedChains
+		if len(Chains) == 0 && Config.InsecureSkipVerify && len(State.PeerCertificates) != 0 {
+			Chains = [][]*x509.Certificate{State.PeerCertificates[:1]}
+		}
+		var Got []string
+		Seen := map[string]bool{}
+		for _, Chain := range Chains {
+			for _, v := range Chain {
+				Pin := PublicKeyPin(v)
+				if Allowed[Pin] {
+					return nil
+				}
+				if !Seen[Pin] {
+					Seen[Pin] = true
+					Got = append(Got, Pin)
+				}
+			}
+		}
+		return &PinMismatchError{Host: State.ServerName, Expected: Pins, Got: Got}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package structuredhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tlsClient creates a client which trusts the test server.
+func tlsClient(t *testing.T, server *httptest.Server) *Client {
+	client := NewClient()
+	if err := client.RootCAs(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestTLSRootCAsAndPinning(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The system pool does not trust the test server.
+	if _, err := NewClient().GET(server.URL).Run(); err == nil {
+		t.Error("Expected the certificate to be untrusted.")
+	}
+
+	// The right pin is accepted.
+	client := tlsClient(t, server)
+	if err := client.PinPublicKeys("sha256/bad", PublicKeyPin(server.Certificate())); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if _, err := client.GET(server.URL).Run(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// The wrong pin is rejected with a PinMismatchError.
+	client = tlsClient(t, server)
+	_ = client.PinPublicKeys("sha256/bad")
+	_, err := client.GET(server.URL).Run()
+	var mismatch *PinMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Error("Expected a PinMismatchError, got", err)
+		return
+	}
+	if mismatch.Got[0] != PublicKeyPin(server.Certificate()) {
+		t.Error("Expected the server pin in the error, got", mismatch.Got)
+	}
+}
+
+func TestTLSServerNameAndVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.ServerName))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	// The test certificate is valid for example.com.
+	client := tlsClient(t, server)
+	_ = client.ServerName("example.com")
+	response, err := client.GET(server.URL).Run()
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if text, _ := response.Text(); text != "example.com" {
+		t.Error("Expected SNI to be example.com, got", text)
+	}
+
+	// The server does not support TLS 1.3.
+	client = tlsClient(t, server)
+	_ = client.MinTLSVersion(tls.VersionTLS13)
+	if _, err = client.GET(server.URL).Run(); err == nil {
+		t.Error("Expected the handshake to fail.")
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	// Create a client certificate.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	_ = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	// Start a server which requires it.
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+
+	client := tlsClient(t, server)
+	if _, err = client.GET(server.URL).Run(); err == nil {
+		t.Error("Expected the request without a certificate to fail.")
+	}
+	client = tlsClient(t, server)
+	if err = client.ClientCertificate(certFile, keyFile); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if _, err = client.GET(server.URL).Run(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// createTestCertificate creates a certificate for 127.0.0.1 signed by the parent specified, or self-signed if the
+// parent is nil.
+func createTestCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, ca bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	if ca {
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func TestTLSPinningIgnoresUnverifiedCertificates(t *testing.T) {
+	// The server has a leaf from a trusted CA, and also sends an unrelated certificate with the pinned key.
+	ca, caKey := createTestCertificate(t, nil, nil, true)
+	leaf, leafKey := createTestCertificate(t, ca, caKey, false)
+	pinned, _ := createTestCertificate(t, nil, nil, false)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{leaf.Raw, pinned.Raw},
+		PrivateKey:  leafKey,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient()
+	_ = client.RootCAs(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))
+	_ = client.PinPublicKeys(PublicKeyPin(pinned))
+	_, err := client.GET(server.URL).Run()
+	var mismatch *PinMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Error("Expected a PinMismatchError, got", err)
+		return
+	}
+	if len(mismatch.Got) != 2 || mismatch.Got[0] != PublicKeyPin(leaf) || mismatch.Got[1] != PublicKeyPin(ca) {
+		t.Error("Expected the pins of the verified chain, got", mismatch.Got)
+	}
+
+	// The CA of the verified chain can be pinned.
+	client = NewClient()
+	_ = client.RootCAs(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))
+	_ = client.PinPublicKeys(PublicKeyPin(ca))
+	if _, err = client.GET(server.URL).Run(); err != nil {
+		t.Error(err.Error())
+	}
+
+	// Without verification, only the leaf is checked.
+	for _, v := range []struct {
+		pin string
+		ok  bool
+	}{{PublicKeyPin(pinned), false}, {PublicKeyPin(ca), false}, {PublicKeyPin(leaf), true}} {
+		client = NewClient()
+		Config, _ := client.TLSConfig()
+		Config.InsecureSkipVerify = true
+		_ = client.PinPublicKeys(v.pin)
+		_, err = client.GET(server.URL).Run()
+		if v.ok && err != nil {
+			t.Error(err.Error())
+		} else if !v.ok && !errors.As(err, &mismatch) {
+			t.Error("Expected a PinMismatchError without verification, got", err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package vcr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"unicode/utf8"
+)
+
+// Body is a recorded body. Bodies which are valid UTF-8 are stored as text so cassettes are readable, and other
+// bodies are stored as base64.
+type Body []byte
+
+// bodyJSON is the JSON representation of a Body.
+type bodyJSON struct {
+	Text   string `json:"text,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(bodyJSON{Text: string(b)})
+	}
+	return json.Marshal(bodyJSON{Base64: base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Body) UnmarshalJSON(Data []byte) error {
+	var j bodyJSON
+	if err := json.Unmarshal(Data, &j); err != nil {
+		return err
+	}
+	if j.Base64 == "" {
+		*b = Body(j.Text)
+		return nil
+	}
+	Decoded, err := base64.StdEncoding.DecodeString(j.Base64)
+	*b = Decoded
+	return err
+}
+
+// RecordedRequest is a request in a cassette.
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    Body        `json:"body"`
+}
+
+// RecordedResponse is a response in a cassette.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Headers    http.Header `json:"headers"`
+	Body       Body        `json:"body"`
+}
+
+// Interaction is a request and the response which was returned for it.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette is a set of recorded interactions.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// LoadCassette loads a cassette from the path specified.
+func LoadCassette(Path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(Path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save writes the cassette to the path specified.
+func (c *Cassette) Save(Path string) error {
+	b, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(Path, b, 0644)
+}
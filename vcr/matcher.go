@@ -0,0 +1,48 @@
+package vcr
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// Matcher is used to check if a recorded interaction matches a request. Body is the body of the request.
+type Matcher func(r *structuredhttp.Request, Body []byte, i *Interaction) bool
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(r *structuredhttp.Request, _ []byte, i *Interaction) bool {
+	return r.Method == i.Request.Method
+}
+
+// MatchURL matches requests with the same URL.
+func MatchURL(r *structuredhttp.Request, _ []byte, i *Interaction) bool {
+	return r.URL == i.Request.URL
+}
+
+// MatchBody matches requests with the same body.
+func MatchBody(_ *structuredhttp.Request, Body []byte, i *Interaction) bool {
+	return bytes.Equal(Body, i.Request.Body)
+}
+
+// MatchHeaders returns a matcher which matches requests with the same values for the headers specified.
+func MatchHeaders(Headers ...string) Matcher {
+	return func(r *structuredhttp.Request, _ []byte, i *Interaction) bool {
+		h := requestHeaders(r)
+		for _, k := range Headers {
+			if h.Get(k) != i.Request.Headers.Get(k) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// requestHeaders is used to get the headers of a request as a http.Header.
+func requestHeaders(r *structuredhttp.Request) http.Header {
+	h := http.Header{}
+	for k, v := range r.Headers {
+		h.Set(k, v)
+	}
+	return h
+}
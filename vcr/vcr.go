@@ -0,0 +1,205 @@
+// Package vcr records the requests made with structuredhttp to a cassette file and replays them, so tests can run
+// without the network. The recorder is middleware, so it can be added to a request with Use, or to a Client or
+// RouteHandler with their Middleware attributes:
+//
+//	recorder, err := vcr.New("testdata/users.json", vcr.ModeReplayOrRecord)
+//	...
+//	defer recorder.Save()
+//	handler := structuredhttp.RouteHandler{BaseURL: URL, Middleware: []structuredhttp.Middleware{recorder.Middleware}}
+package vcr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+// Mode defines whether the recorder replays or records interactions.
+type Mode int
+
+const (
+	// ModeReplay only replays interactions from the cassette. Requests which do not match fail with
+	// ErrInteractionNotFound.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends every request and records it, replacing the interactions in the cassette.
+	ModeRecord
+
+	// ModeReplayOrRecord replays interactions from the cassette, and sends and records requests which do not match.
+	ModeReplayOrRecord
+)
+
+// ErrInteractionNotFound is returned in replay mode when there is no interaction in the cassette for a request.
+var ErrInteractionNotFound = errors.New("vcr: no recorded interaction matches the request")
+
+// Recorder records and replays interactions.
+type Recorder struct {
+	// Path is the path of the cassette file.
+	Path string
+
+	// Mode is whether the recorder replays or records interactions.
+	Mode Mode
+
+	// Matchers are used to find the interaction for a request. All of them must match. If this is nil, the method
+	// and URL are matched.
+	Matchers []Matcher
+
+	// BeforeSave is called with each new interaction before it is stored, which can be used to remove secrets.
+	BeforeSave func(i *Interaction)
+
+	// Cassette is the cassette which is replayed and recorded to.
+	Cassette *Cassette
+
+	lock sync.Mutex
+	used map[*Interaction]bool
+}
+
+// New creates a recorder for the cassette at the path specified. The cassette is loaded if it exists. In replay
+// mode, the cassette must exist.
+func New(Path string, Mode Mode) (*Recorder, error) {
+	r := &Recorder{Path: Path, Mode: Mode, Cassette: &Cassette{}}
+	if Mode == ModeRecord {
+		return r, nil
+	}
+	c, err := LoadCassette(Path)
+	if err != nil {
+		if os.IsNotExist(err) && Mode == ModeReplayOrRecord {
+			return r, nil
+		}
+		return nil, err
+	}
+	r.Cassette = c
+	return r, nil
+}
+
+// Save writes the cassette to the path of the recorder.
+func (r *Recorder) Save() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.Cassette.Save(r.Path)
+}
+
+// match is used to find the interaction for a request. Interactions which have not been replayed yet are used first,
+// so repeated requests are replayed in the order they were recorded.
+func (r *Recorder) match(req *structuredhttp.Request, Body []byte) *Interaction {
+	Matchers := r.Matchers
+	if Matchers == nil {
+		Matchers = []Matcher{MatchMethod, MatchURL}
+	}
+	var Fallback *Interaction
+	for _, i := range r.Cassette.Interactions {
+		Matched := true
+		for _, m := range Matchers {
+			if !m(req, Body, i) {
+				Matched = false
+				break
+			}
+		}
+		if !Matched {
+			continue
+		}
+		if !r.used[i] {
+			return i
+		}
+		if Fallback == nil {
+			Fallback = i
+		}
+	}
+	return Fallback
+}
+
+// Middleware is the middleware which replays and records requests.
+func (r *Recorder) Middleware(next structuredhttp.RoundTrip) structuredhttp.RoundTrip {
+	return func(ctx context.Context, req *structuredhttp.Request) (*structuredhttp.Response, error) {
+		Body, err := req.BodyBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		// Try to replay the request.
+		if r.Mode != ModeRecord {
+			r.lock.Lock()
+			i := r.match(req, Body)
+			if i != nil {
+				if r.used == nil {
+					r.used = map[*Interaction]bool{}
+				}
+				r.used[i] = true
+			}
+			r.lock.Unlock()
+			if i != nil {
+				return replay(ctx, req, i)
+			}
+			if r.Mode == ModeReplay {
+				return nil, ErrInteractionNotFound
+			}
+		}
+
+		// Send the request and record the response.
+		res, err := next(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		ResponseBody, err := ioutil.ReadAll(res.RawResponse.Body)
+		_ = res.RawResponse.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		res.RawResponse.Body = ioutil.NopCloser(bytes.NewReader(ResponseBody))
+		i := &Interaction{
+			Request: RecordedRequest{
+				Method:  req.Method,
+				URL:     req.URL,
+				Headers: requestHeaders(req),
+				Body:    Body,
+			},
+			Response: RecordedResponse{
+				StatusCode: res.RawResponse.StatusCode,
+				Status:     res.RawResponse.Status,
+				Headers:    res.RawResponse.Header.Clone(),
+				Body:       ResponseBody,
+			},
+		}
+		if r.BeforeSave != nil {
+			r.BeforeSave(i)
+		}
+		r.lock.Lock()
+		r.Cassette.Interactions = append(r.Cassette.Interactions, i)
+		if r.used == nil {
+			r.used = map[*Interaction]bool{}
+		}
+		r.used[i] = true
+		r.lock.Unlock()
+		return res, nil
+	}
+}
+
+// replay is used to create the response for a recorded interaction.
+func replay(ctx context.Context, req *structuredhttp.Request, i *Interaction) (*structuredhttp.Response, error) {
+	RawRequest, err := http.NewRequestWithContext(ctx, req.Method, req.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	Status := i.Response.Status
+	if Status == "" {
+		Status = strconv.Itoa(i.Response.StatusCode) + " " + http.StatusText(i.Response.StatusCode)
+	}
+	return &structuredhttp.Response{RawResponse: &http.Response{
+		Status:        Status,
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Response.Headers.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       RawRequest,
+	}}, nil
+}
@@ -0,0 +1,84 @@
+package vcr
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakemakesstuff/structuredhttp"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"call": ` + string(rune('0'+calls)) + `}`))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Record two requests to the same URL.
+	recorder, err := New(path, ModeRecord)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	recorder.BeforeSave = func(i *Interaction) {
+		i.Request.Headers.Del("Authorization")
+	}
+	handler := structuredhttp.RouteHandler{
+		BaseURL:    server.URL,
+		Headers:    map[string]string{"Authorization": "secret"},
+		Middleware: []structuredhttp.Middleware{recorder.Middleware},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = handler.POST("/users").JSON(map[string]string{"name": "a"}).Run(); err != nil {
+			t.Error(err.Error())
+			return
+		}
+	}
+	if err = recorder.Save(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	server.Close()
+
+	// Replay them without the server.
+	recorder, err = New(path, ModeReplay)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	recorder.Matchers = []Matcher{MatchMethod, MatchURL, MatchBody}
+	handler.Middleware = []structuredhttp.Middleware{recorder.Middleware}
+	if recorder.Cassette.Interactions[0].Request.Headers.Get("Authorization") != "" {
+		t.Error("Expected the secret to be removed.")
+		return
+	}
+	for i := 1; i <= 2; i++ {
+		response, err := handler.POST("/users").JSON(map[string]string{"name": "a"}).Run()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		var body map[string]int
+		if err = response.Decode(&body); err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if response.RawResponse.StatusCode != http.StatusCreated || body["call"] != i {
+			t.Error("Invalid response replayed", body)
+			return
+		}
+	}
+
+	_, err = handler.POST("/users").JSON(map[string]string{"name": "b"}).Run()
+	if !errors.Is(err, ErrInteractionNotFound) {
+		t.Error("Expected no interaction to be found, got", err)
+		return
+	}
+	t.Log("Record and replay works!")
+}